@@ -0,0 +1,322 @@
+/*
+Copyright 2024 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storagequeues
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azqueue"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+const fakeQueueServiceURL = "https://devstoreaccount1.queue.core.windows.net/"
+
+// fakeQueueService is a minimal, in-memory implementation of the Azure Storage Queues REST API.
+// It is used as transport by the queue clients in tests.
+type fakeQueueService struct {
+	queues   map[string][]*fakeQueueMessage
+	requests []fakeQueueRequest
+	lock     sync.Mutex
+}
+
+type fakeQueueRequest struct {
+	Method string
+	Path   string
+	Query  url.Values
+	// For requests that dequeue or peek messages, the number of messages returned
+	Returned int
+}
+
+type fakeQueueMessage struct {
+	ID           string
+	PopReceipt   string
+	Text         string
+	Inserted     time.Time
+	Expires      time.Time
+	VisibleAt    time.Time
+	DequeueCount int64
+}
+
+type fakeQueueMessageXML struct {
+	MessageID       string `xml:"MessageId"`
+	InsertionTime   string `xml:"InsertionTime"`
+	ExpirationTime  string `xml:"ExpirationTime"`
+	PopReceipt      string `xml:"PopReceipt,omitempty"`
+	TimeNextVisible string `xml:"TimeNextVisible,omitempty"`
+	DequeueCount    *int64 `xml:"DequeueCount,omitempty"`
+	MessageText     string `xml:"MessageText,omitempty"`
+}
+
+type fakeQueueMessagesListXML struct {
+	XMLName  xml.Name              `xml:"QueueMessagesList"`
+	Messages []fakeQueueMessageXML `xml:"QueueMessage"`
+}
+
+func newFakeQueueService() *fakeQueueService {
+	return &fakeQueueService{
+		queues: map[string][]*fakeQueueMessage{},
+	}
+}
+
+// NewQueueClient returns a client for a queue in the fake service.
+func (f *fakeQueueService) NewQueueClient(t *testing.T, queueName string) *azqueue.QueueClient {
+	t.Helper()
+
+	client, err := azqueue.NewQueueClientWithNoCredential(fakeQueueServiceURL+queueName, f.clientOptions())
+	require.NoError(t, err)
+	return client
+}
+
+// NewServiceClient returns a client for the fake service.
+func (f *fakeQueueService) NewServiceClient(t *testing.T) *azqueue.ServiceClient {
+	t.Helper()
+
+	client, err := azqueue.NewServiceClientWithNoCredential(fakeQueueServiceURL, f.clientOptions())
+	require.NoError(t, err)
+	return client
+}
+
+func (f *fakeQueueService) clientOptions() *azqueue.ClientOptions {
+	return &azqueue.ClientOptions{
+		ClientOptions: policy.ClientOptions{
+			Transport: f,
+			Retry: policy.RetryOptions{
+				MaxRetries: -1,
+			},
+		},
+	}
+}
+
+// Messages returns the text of all messages in the queue, including invisible ones.
+func (f *fakeQueueService) Messages(queueName string) []string {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	res := make([]string, len(f.queues[queueName]))
+	for i, msg := range f.queues[queueName] {
+		res[i] = msg.Text
+	}
+	return res
+}
+
+// MakeVisible makes all messages in the queue visible again, as if their visibility timeout had expired.
+func (f *fakeQueueService) MakeVisible(queueName string) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	now := time.Now()
+	for _, msg := range f.queues[queueName] {
+		msg.VisibleAt = now
+	}
+}
+
+// Requests returns the requests received with the given method and path.
+func (f *fakeQueueService) Requests(method string, path string) []fakeQueueRequest {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	res := []fakeQueueRequest{}
+	for _, r := range f.requests {
+		if r.Method == method && r.Path == path {
+			res = append(res, r)
+		}
+	}
+	return res
+}
+
+// Do implements policy.Transporter.
+func (f *fakeQueueService) Do(req *http.Request) (*http.Response, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	path := strings.Trim(req.URL.Path, "/")
+	query := req.URL.Query()
+	f.requests = append(f.requests, fakeQueueRequest{
+		Method: req.Method,
+		Path:   path,
+		Query:  query,
+	})
+
+	parts := strings.Split(path, "/")
+	queueName := parts[0]
+	queue, exists := f.queues[queueName]
+	now := time.Now()
+
+	switch {
+	case len(parts) == 1 && req.Method == http.MethodPut:
+		// Create queue
+		if exists {
+			return f.response(req, http.StatusNoContent, nil, nil), nil
+		}
+		f.queues[queueName] = []*fakeQueueMessage{}
+		return f.response(req, http.StatusCreated, nil, nil), nil
+
+	case !exists:
+		return f.errorResponse(req, http.StatusNotFound, "QueueNotFound"), nil
+
+	case len(parts) == 2 && req.Method == http.MethodPost:
+		// Enqueue message
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		var in struct {
+			MessageText string `xml:"MessageText"`
+		}
+		err = xml.Unmarshal(body, &in)
+		if err != nil {
+			return f.errorResponse(req, http.StatusBadRequest, "InvalidXmlDocument"), nil
+		}
+		ttl := 7 * 24 * time.Hour
+		if v := query.Get("messagettl"); v != "" {
+			n, _ := strconv.Atoi(v)
+			ttl = time.Duration(n) * time.Second
+			if n == -1 {
+				// Message never expires
+				ttl = 100 * 365 * 24 * time.Hour
+			}
+		}
+		msg := &fakeQueueMessage{
+			ID:         uuid.NewString(),
+			PopReceipt: uuid.NewString(),
+			Text:       in.MessageText,
+			Inserted:   now,
+			Expires:    now.Add(ttl),
+			VisibleAt:  now,
+		}
+		f.queues[queueName] = append(queue, msg)
+		return f.response(req, http.StatusCreated, nil, &fakeQueueMessagesListXML{
+			Messages: []fakeQueueMessageXML{{
+				MessageID:       msg.ID,
+				InsertionTime:   msg.Inserted.UTC().Format(http.TimeFormat),
+				ExpirationTime:  msg.Expires.UTC().Format(http.TimeFormat),
+				PopReceipt:      msg.PopReceipt,
+				TimeNextVisible: msg.VisibleAt.UTC().Format(http.TimeFormat),
+			}},
+		}), nil
+
+	case len(parts) == 2 && req.Method == http.MethodGet:
+		// Dequeue or peek messages
+		count := 1
+		if v := query.Get("numofmessages"); v != "" {
+			count, _ = strconv.Atoi(v)
+		}
+		peek := query.Get("peekonly") == "true"
+		visibilityTimeout := 30 * time.Second
+		if v := query.Get("visibilitytimeout"); v != "" {
+			n, _ := strconv.Atoi(v)
+			visibilityTimeout = time.Duration(n) * time.Second
+		}
+
+		out := &fakeQueueMessagesListXML{}
+		for _, msg := range queue {
+			if len(out.Messages) >= count {
+				break
+			}
+			if msg.VisibleAt.After(now) || msg.Expires.Before(now) {
+				continue
+			}
+			item := fakeQueueMessageXML{
+				MessageID:      msg.ID,
+				InsertionTime:  msg.Inserted.UTC().Format(http.TimeFormat),
+				ExpirationTime: msg.Expires.UTC().Format(http.TimeFormat),
+				MessageText:    msg.Text,
+			}
+			if !peek {
+				msg.DequeueCount++
+				msg.PopReceipt = uuid.NewString()
+				msg.VisibleAt = now.Add(visibilityTimeout)
+				item.PopReceipt = msg.PopReceipt
+				item.TimeNextVisible = msg.VisibleAt.UTC().Format(http.TimeFormat)
+			}
+			dequeueCount := msg.DequeueCount
+			item.DequeueCount = &dequeueCount
+			out.Messages = append(out.Messages, item)
+		}
+		f.requests[len(f.requests)-1].Returned = len(out.Messages)
+		return f.response(req, http.StatusOK, nil, out), nil
+
+	case len(parts) == 3 && (req.Method == http.MethodDelete || req.Method == http.MethodPut):
+		// Delete or update message
+		idx := -1
+		for i, msg := range queue {
+			if msg.ID == parts[2] {
+				idx = i
+				break
+			}
+		}
+		if idx < 0 {
+			return f.errorResponse(req, http.StatusNotFound, "MessageNotFound"), nil
+		}
+		msg := queue[idx]
+		if msg.PopReceipt != query.Get("popreceipt") {
+			return f.errorResponse(req, http.StatusBadRequest, "PopReceiptMismatch"), nil
+		}
+
+		if req.Method == http.MethodDelete {
+			f.queues[queueName] = append(queue[:idx:idx], queue[idx+1:]...)
+			return f.response(req, http.StatusNoContent, nil, nil), nil
+		}
+
+		n, _ := strconv.Atoi(query.Get("visibilitytimeout"))
+		msg.VisibleAt = now.Add(time.Duration(n) * time.Second)
+		msg.PopReceipt = uuid.NewString()
+		return f.response(req, http.StatusNoContent, map[string]string{
+			"x-ms-popreceipt":        msg.PopReceipt,
+			"x-ms-time-next-visible": msg.VisibleAt.UTC().Format(http.TimeFormat),
+		}, nil), nil
+	}
+
+	return f.errorResponse(req, http.StatusBadRequest, "UnsupportedHttpVerb"), nil
+}
+
+func (f *fakeQueueService) response(req *http.Request, status int, headers map[string]string, body any) *http.Response {
+	res := &http.Response{
+		StatusCode: status,
+		Header:     http.Header{},
+		Request:    req,
+		Body:       http.NoBody,
+	}
+	for k, v := range headers {
+		res.Header.Set(k, v)
+	}
+	if body != nil {
+		enc, _ := xml.Marshal(body)
+		res.Header.Set("Content-Type", "application/xml")
+		res.Body = io.NopCloser(bytes.NewReader(enc))
+		res.ContentLength = int64(len(enc))
+	}
+	return res
+}
+
+func (f *fakeQueueService) errorResponse(req *http.Request, status int, code string) *http.Response {
+	res := f.response(req, status, map[string]string{
+		"x-ms-error-code": code,
+	}, &struct {
+		XMLName xml.Name `xml:"Error"`
+		Code    string   `xml:"Code"`
+	}{Code: code})
+	return res
+}
@@ -16,13 +16,16 @@ package storagequeues
 import (
 	"context"
 	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"reflect"
+	"runtime/debug"
 	"strconv"
 	"sync"
 	"sync/atomic"
 	"time"
+	"unicode/utf8"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azqueue"
@@ -36,19 +39,65 @@ import (
 )
 
 const (
-	defaultTTL               = 10 * time.Minute
-	defaultVisibilityTimeout = 30 * time.Second
-	defaultPollingInterval   = 10 * time.Second
-	dequeueCount             = "dequeueCount"
-	insertionTime            = "insertionTime"
-	expirationTime           = "expirationTime"
-	nextVisibleTime          = "nextVisibleTime"
-	popReceipt               = "popReceipt"
-	messageID                = "messageID"
+	defaultTTL                = 10 * time.Minute
+	defaultVisibilityTimeout  = 30 * time.Second
+	maxVisibilityTimeout      = 7 * 24 * time.Hour
+	defaultPollingInterval    = 10 * time.Second
+	defaultMinPollingInterval = time.Second
+	defaultMaxDequeueCount    = 5
+	maxBatchSize              = 32
+	minPollingInterval        = 100 * time.Millisecond
+	numberOfMessages          = "numberOfMessages"
+)
+
+// Keys of the metadata properties added to messages received by the input binding.
+// These are part of the public interface of the binding and must not be changed.
+// messageID and popReceipt are also used by the delete operation.
+const (
+	// ID of the message.
+	messageID = "messageID"
+	// Pop receipt returned by the last dequeue operation, which is required to delete or update the message.
+	popReceipt = "popReceipt"
+	// Time the message was added to the queue, in RFC 3339 format.
+	insertionTime = "insertionTime"
+	// Time the message expires, in RFC 3339 format.
+	expirationTime = "expirationTime"
+	// Time the message will become visible again if it's not deleted, in RFC 3339 format.
+	nextVisibleTime = "nextVisibleTime"
+	// Number of times the message has been dequeued, including the current one.
+	dequeueCount = "dequeueCount"
+
+	// Operation to retrieve messages without removing them from the queue.
+	peekOperation bindings.OperationKind = "peek"
 )
 
 type consumer struct {
 	callback bindings.Handler
+	logger   logger.Logger
+}
+
+// Invokes the handler, recovering from panics, which are returned as errors.
+// This way, a panicking handler is treated like one that returned an error, and the message is left in the queue for redelivery.
+func (c *consumer) invoke(ctx context.Context, res *bindings.ReadResponse) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			c.logger.Errorf("Recovered from panic in handler: %v\n%s", r, debug.Stack())
+			err = fmt.Errorf("panic in handler: %v", r)
+		}
+	}()
+
+	_, err = c.callback(ctx, res)
+	return err
+}
+
+// peekedMessage is a message returned by the peek operation.
+type peekedMessage struct {
+	MessageID      string     `json:"messageID"`
+	InsertionTime  *time.Time `json:"insertionTime,omitempty"`
+	ExpirationTime *time.Time `json:"expirationTime,omitempty"`
+	DequeueCount   int64      `json:"dequeueCount"`
+	// Message text, as stored in the queue (it is not base64-decoded, even when decodeBase64 is set)
+	MessageText string `json:"messageText"`
 }
 
 // QueueHelper enables injection for testnig.
@@ -56,17 +105,29 @@ type QueueHelper interface {
 	Init(ctx context.Context, metadata bindings.Metadata) (*storageQueuesMetadata, error)
 	Write(ctx context.Context, data []byte, ttl *time.Duration) error
 	Read(ctx context.Context, consumer *consumer) error
+	Delete(ctx context.Context, messageID string, popReceipt string) error
+	Peek(ctx context.Context, count int32) ([]peekedMessage, error)
 	Close() error
 }
 
 // AzureQueueHelper concrete impl of queue helper.
 type AzureQueueHelper struct {
 	queueClient       *azqueue.QueueClient
+	deadLetterClient  *azqueue.QueueClient
+	maxDequeueCount   int64
 	logger            logger.Logger
 	decodeBase64      bool
 	encodeBase64      bool
 	pollingInterval   time.Duration
 	visibilityTimeout time.Duration
+	strictOrdering    bool
+	batchSize         int32
+
+	// Polling interval to wait for when the queue is empty; adjusted as messages arrive or the queue stays empty
+	// This is shared by all workers reading from the queue
+	currentPollingInterval time.Duration
+	minPollingInterval     time.Duration
+	pollingLock            sync.Mutex
 }
 
 // Init sets up this helper.
@@ -91,7 +152,7 @@ func (d *AzureQueueHelper) Init(ctx context.Context, meta bindings.Metadata) (*s
 	}
 
 	var queueServiceClient *azqueue.ServiceClient
-	if m.AccountKey != "" && m.AccountName != "" {
+	if m.useSharedKey() {
 		var credential *azqueue.SharedKeyCredential
 		credential, err = azqueue.NewSharedKeyCredential(m.AccountName, m.AccountKey)
 		if err != nil {
@@ -113,20 +174,47 @@ func (d *AzureQueueHelper) Init(ctx context.Context, meta bindings.Metadata) (*s
 		}
 	}
 
+	err = d.initWithClient(ctx, m, queueServiceClient)
+	if err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// Configures the helper from the parsed metadata, and creates the queue using the service client.
+func (d *AzureQueueHelper) initWithClient(ctx context.Context, m *storageQueuesMetadata, queueServiceClient *azqueue.ServiceClient) error {
 	d.decodeBase64 = m.DecodeBase64
 	d.encodeBase64 = m.EncodeBase64
 	d.pollingInterval = m.PollingInterval
+	d.minPollingInterval = m.MinPollingInterval
+	d.currentPollingInterval = m.MinPollingInterval
 	d.visibilityTimeout = *m.VisibilityTimeout
+	d.strictOrdering = m.StrictOrdering
+	d.batchSize = m.BatchSize
 	d.queueClient = queueServiceClient.NewQueueClient(m.QueueName)
 
 	createCtx, createCancel := context.WithTimeout(ctx, 2*time.Minute)
-	_, err = d.queueClient.Create(createCtx, nil)
+	_, err := d.queueClient.Create(createCtx, nil)
 	createCancel()
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	return m, nil
+	// Create the dead-letter queue if configured
+	if m.DeadLetterQueueName != "" {
+		d.maxDequeueCount = m.MaxDequeueCount
+		d.deadLetterClient = queueServiceClient.NewQueueClient(m.DeadLetterQueueName)
+
+		createCtx, createCancel = context.WithTimeout(ctx, 2*time.Minute)
+		_, err = d.deadLetterClient.Create(createCtx, nil)
+		createCancel()
+		if err != nil {
+			return fmt.Errorf("failed to create dead-letter queue: %w", err)
+		}
+	}
+
+	return nil
 }
 
 func (d *AzureQueueHelper) Write(ctx context.Context, data []byte, ttl *time.Duration) error {
@@ -137,16 +225,21 @@ func (d *AzureQueueHelper) Write(ctx context.Context, data []byte, ttl *time.Dur
 		ttlSeconds = ptr.Of(int32(defaultTTL.Seconds()))
 	}
 
-	s, err := strconv.Unquote(string(data))
-	if err != nil {
-		s = string(data)
+	// Data sent as a JSON string is unquoted
+	// Binary data (that isn't valid UTF-8) is left as-is, as unquoting it could corrupt it
+	s := string(data)
+	if utf8.Valid(data) {
+		unquoted, err := strconv.Unquote(s)
+		if err == nil {
+			s = unquoted
+		}
 	}
 
 	if d.encodeBase64 {
 		s = base64.StdEncoding.EncodeToString([]byte(s))
 	}
 
-	_, err = d.queueClient.EnqueueMessage(ctx, s, &azqueue.EnqueueMessageOptions{
+	_, err := d.queueClient.EnqueueMessage(ctx, s, &azqueue.EnqueueMessageOptions{
 		TimeToLive: ttlSeconds,
 	})
 
@@ -155,21 +248,43 @@ func (d *AzureQueueHelper) Write(ctx context.Context, data []byte, ttl *time.Dur
 
 func (d *AzureQueueHelper) Read(ctx context.Context, consumer *consumer) error {
 	res, err := d.queueClient.DequeueMessages(ctx, &azqueue.DequeueMessagesOptions{
-		NumberOfMessages:  ptr.Of(int32(1)),
+		NumberOfMessages:  ptr.Of(d.batchSize),
 		VisibilityTimeout: ptr.Of(int32(d.visibilityTimeout.Seconds())),
 	})
 	if err != nil {
 		return err
 	}
 	if len(res.Messages) == 0 {
-		// Queue was empty so back off seconds before trying again
+		// Queue was empty so back off before trying again
+		t := time.NewTimer(d.nextPollingInterval(true))
 		select {
-		case <-time.After(d.pollingInterval):
+		case <-t.C:
 		case <-ctx.Done():
+			t.Stop()
 		}
 		return nil
 	}
-	mt := res.Messages[0].MessageText
+	d.nextPollingInterval(false)
+
+	// Process the messages in the batch one at a time
+	errs := make([]error, 0)
+	for _, msg := range res.Messages {
+		err = d.processMessage(ctx, consumer, msg)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Processes a message that was dequeued, deleting it from the queue if the handler was successful.
+func (d *AzureQueueHelper) processMessage(ctx context.Context, consumer *consumer, msg *azqueue.DequeuedMessage) error {
+	// If the message has been delivered too many times, move it to the dead-letter queue without processing it
+	if d.deadLetterClient != nil && msg.DequeueCount != nil && *msg.DequeueCount > d.maxDequeueCount {
+		return d.deadLetterMessage(ctx, msg)
+	}
+
+	mt := msg.MessageText
 
 	data := []byte("")
 	if mt != nil {
@@ -186,35 +301,38 @@ func (d *AzureQueueHelper) Read(ctx context.Context, consumer *consumer) error {
 
 	metadata := make(map[string]string, 6)
 
-	if res.Messages[0].MessageID != nil {
-		metadata[messageID] = *res.Messages[0].MessageID
+	if msg.MessageID != nil {
+		metadata[messageID] = *msg.MessageID
 	}
-	if res.Messages[0].PopReceipt != nil {
-		metadata[popReceipt] = *res.Messages[0].PopReceipt
+	if msg.PopReceipt != nil {
+		metadata[popReceipt] = *msg.PopReceipt
 	}
-	if res.Messages[0].InsertionTime != nil {
-		metadata[insertionTime] = res.Messages[0].InsertionTime.Format(time.RFC3339)
+	if msg.InsertionTime != nil {
+		metadata[insertionTime] = msg.InsertionTime.Format(time.RFC3339)
 	}
-	if res.Messages[0].ExpirationTime != nil {
-		metadata[expirationTime] = res.Messages[0].ExpirationTime.Format(time.RFC3339)
+	if msg.ExpirationTime != nil {
+		metadata[expirationTime] = msg.ExpirationTime.Format(time.RFC3339)
 	}
-	if res.Messages[0].TimeNextVisible != nil {
-		metadata[nextVisibleTime] = res.Messages[0].TimeNextVisible.Format(time.RFC3339)
+	if msg.TimeNextVisible != nil {
+		metadata[nextVisibleTime] = msg.TimeNextVisible.Format(time.RFC3339)
 	}
-	if res.Messages[0].DequeueCount != nil {
-		metadata[dequeueCount] = strconv.FormatInt(*res.Messages[0].DequeueCount, 10)
+	if msg.DequeueCount != nil {
+		metadata[dequeueCount] = strconv.FormatInt(*msg.DequeueCount, 10)
 	}
 
-	_, err = consumer.callback(ctx, &bindings.ReadResponse{
+	err := consumer.invoke(ctx, &bindings.ReadResponse{
 		Data:     data,
 		Metadata: metadata,
 	})
 	if err != nil {
+		if d.strictOrdering {
+			d.releaseMessage(ctx, msg)
+		}
 		return err
 	}
 
-	if res.Messages[0].MessageID != nil && res.Messages[0].PopReceipt != nil {
-		_, err = d.queueClient.DeleteMessage(ctx, *res.Messages[0].MessageID, *res.Messages[0].PopReceipt, nil)
+	if msg.MessageID != nil && msg.PopReceipt != nil {
+		_, err = d.queueClient.DeleteMessage(ctx, *msg.MessageID, *msg.PopReceipt, nil)
 		if err != nil {
 			return err
 		}
@@ -224,6 +342,110 @@ func (d *AzureQueueHelper) Read(ctx context.Context, consumer *consumer) error {
 	}
 }
 
+// Delete removes a message from the queue.
+func (d *AzureQueueHelper) Delete(ctx context.Context, messageID string, popReceipt string) error {
+	_, err := d.queueClient.DeleteMessage(ctx, messageID, popReceipt, nil)
+	return err
+}
+
+// Peek returns up to count messages from the front of the queue, without changing their visibility.
+func (d *AzureQueueHelper) Peek(ctx context.Context, count int32) ([]peekedMessage, error) {
+	res, err := d.queueClient.PeekMessages(ctx, &azqueue.PeekMessagesOptions{
+		NumberOfMessages: ptr.Of(count),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	msgs := make([]peekedMessage, 0, len(res.Messages))
+	for _, msg := range res.Messages {
+		if msg == nil || msg.MessageID == nil {
+			continue
+		}
+		item := peekedMessage{
+			MessageID:      *msg.MessageID,
+			InsertionTime:  msg.InsertionTime,
+			ExpirationTime: msg.ExpirationTime,
+		}
+		if msg.DequeueCount != nil {
+			item.DequeueCount = *msg.DequeueCount
+		}
+		if msg.MessageText != nil {
+			item.MessageText = *msg.MessageText
+		}
+		msgs = append(msgs, item)
+	}
+	return msgs, nil
+}
+
+// Moves a message to the dead-letter queue, then deletes it from the queue.
+// The message is copied as-is, and it doesn't expire in the dead-letter queue.
+func (d *AzureQueueHelper) deadLetterMessage(ctx context.Context, msg *azqueue.DequeuedMessage) error {
+	if msg.MessageID == nil || msg.PopReceipt == nil {
+		return errors.New("could not move message to dead-letter queue: message ID or pop receipt is nil")
+	}
+
+	var content string
+	if msg.MessageText != nil {
+		content = *msg.MessageText
+	}
+	_, err := d.deadLetterClient.EnqueueMessage(ctx, content, &azqueue.EnqueueMessageOptions{
+		TimeToLive: ptr.Of(int32(-1)),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to move message %s to dead-letter queue: %w", *msg.MessageID, err)
+	}
+
+	_, err = d.queueClient.DeleteMessage(ctx, *msg.MessageID, *msg.PopReceipt, nil)
+	if err != nil {
+		return fmt.Errorf("failed to delete message %s after moving it to dead-letter queue: %w", *msg.MessageID, err)
+	}
+
+	d.logger.Warnf("Message %s was delivered %d times and was moved to the dead-letter queue", *msg.MessageID, *msg.DequeueCount)
+	return nil
+}
+
+// Makes a message that failed processing visible again right away, so it's the next one to be retrieved (on a best-effort basis).
+// This is used with strict ordering. It also waits before returning, so a message that keeps failing doesn't cause a tight loop.
+func (d *AzureQueueHelper) releaseMessage(ctx context.Context, msg *azqueue.DequeuedMessage) {
+	if msg.MessageID == nil || msg.PopReceipt == nil {
+		return
+	}
+
+	var content string
+	if msg.MessageText != nil {
+		content = *msg.MessageText
+	}
+	_, err := d.queueClient.UpdateMessage(ctx, *msg.MessageID, *msg.PopReceipt, content, &azqueue.UpdateMessageOptions{
+		VisibilityTimeout: ptr.Of(int32(0)),
+	})
+	if err != nil {
+		d.logger.Warnf("Failed to make message %s visible again: %v", *msg.MessageID, err)
+	}
+
+	select {
+	case <-time.After(d.minPollingInterval):
+	case <-ctx.Done():
+	}
+}
+
+// Returns the interval to wait for before polling the queue again, adjusting it based on whether the queue was empty.
+// The first time the queue is found empty, the interval is minPollingInterval; it then doubles every time, up to the maximum (pollingInterval).
+// As soon as messages are received, it's reset to minPollingInterval.
+func (d *AzureQueueHelper) nextPollingInterval(empty bool) time.Duration {
+	d.pollingLock.Lock()
+	defer d.pollingLock.Unlock()
+
+	if !empty {
+		d.currentPollingInterval = d.minPollingInterval
+		return d.currentPollingInterval
+	}
+
+	wait := d.currentPollingInterval
+	d.currentPollingInterval = min(d.currentPollingInterval*2, d.pollingInterval)
+	return wait
+}
+
 func (d *AzureQueueHelper) Close() error {
 	return nil
 }
@@ -248,15 +470,31 @@ type AzureStorageQueues struct {
 }
 
 type storageQueuesMetadata struct {
-	QueueName         string
-	QueueEndpoint     string
-	AccountName       string
-	AccountKey        string
-	DecodeBase64      bool
-	EncodeBase64      bool
-	PollingInterval   time.Duration  `mapstructure:"pollingInterval"`
-	TTL               *time.Duration `mapstructure:"ttl" mapstructurealiases:"ttlInSeconds"`
-	VisibilityTimeout *time.Duration
+	QueueName          string
+	QueueEndpoint      string
+	AccountName        string
+	AccountKey         string
+	DecodeBase64       bool
+	EncodeBase64       bool
+	PollingInterval    time.Duration  `mapstructure:"pollingInterval" mapstructurealiases:"pollingIntervalMax"`
+	MinPollingInterval time.Duration  `mapstructure:"minPollingInterval" mapstructurealiases:"pollingIntervalMin"`
+	StrictOrdering     bool           `mapstructure:"strictOrdering"`
+	TTL                *time.Duration `mapstructure:"ttl" mapstructurealiases:"ttlInSeconds"`
+	VisibilityTimeout  *time.Duration
+	// Name of the queue where messages that fail processing too many times are moved to.
+	// If empty, messages that keep failing are retried until they expire.
+	DeadLetterQueueName string `mapstructure:"deadLetterQueueName"`
+	// Maximum number of times a message is delivered before it's moved to the dead-letter queue.
+	MaxDequeueCount int64 `mapstructure:"maxDequeueCount"`
+	// Number of workers reading messages from the queue concurrently.
+	Concurrency int `mapstructure:"concurrency"`
+	// Number of messages retrieved from the queue with each request, up to 32.
+	BatchSize int32 `mapstructure:"batchSize"`
+}
+
+// Returns true if the binding authenticates with a shared account key; otherwise, Azure AD (Microsoft Entra ID) is used.
+func (m *storageQueuesMetadata) useSharedKey() bool {
+	return m.AccountKey != "" && m.AccountName != ""
 }
 
 func (m *storageQueuesMetadata) GetQueueURL(azEnvSettings azauth.EnvironmentSettings) string {
@@ -290,8 +528,12 @@ func (a *AzureStorageQueues) Init(ctx context.Context, metadata bindings.Metadat
 
 func parseMetadata(meta bindings.Metadata) (*storageQueuesMetadata, error) {
 	m := storageQueuesMetadata{
-		PollingInterval:   defaultPollingInterval,
-		VisibilityTimeout: ptr.Of(defaultVisibilityTimeout),
+		PollingInterval:    defaultPollingInterval,
+		MinPollingInterval: defaultMinPollingInterval,
+		VisibilityTimeout:  ptr.Of(defaultVisibilityTimeout),
+		MaxDequeueCount:    defaultMaxDequeueCount,
+		Concurrency:        1,
+		BatchSize:          1,
 	}
 	err := kitmd.DecodeMetadata(meta.Properties, &m)
 	if err != nil {
@@ -318,9 +560,54 @@ func parseMetadata(meta bindings.Metadata) (*storageQueuesMetadata, error) {
 		m.AccountKey = val
 	}
 
-	if m.PollingInterval < (100 * time.Millisecond) {
+	// An account key can't be used together with credentials for a service principal, as only one of them would be used
+	if m.AccountKey != "" {
+		for _, key := range []string{"ClientSecret", "Certificate", "CertificateFile"} {
+			if val, ok := contribMetadata.GetMetadataProperty(meta.Properties, azauth.MetadataKeys[key]...); ok && val != "" {
+				return nil, fmt.Errorf("only one authentication method can be configured: found both %s and %s", azauth.MetadataKeys["StorageAccountKey"][0], azauth.MetadataKeys[key][0])
+			}
+		}
+	}
+
+	if m.PollingInterval < minPollingInterval {
 		return nil, errors.New("invalid value for 'pollingInterval': must be greater than 100ms")
 	}
+	if _, ok := contribMetadata.GetMetadataProperty(meta.Properties, "minPollingInterval", "pollingIntervalMin"); !ok && m.PollingInterval < m.MinPollingInterval {
+		// If the user has set a polling interval lower than the default minimum, use that as minimum too
+		m.MinPollingInterval = m.PollingInterval
+	}
+	if m.MinPollingInterval < minPollingInterval {
+		return nil, errors.New("invalid value for 'minPollingInterval': must be greater than 100ms")
+	}
+	if m.MinPollingInterval > m.PollingInterval {
+		return nil, errors.New("invalid value for 'minPollingInterval': must not be greater than 'pollingInterval'")
+	}
+
+	if m.VisibilityTimeout == nil {
+		m.VisibilityTimeout = ptr.Of(defaultVisibilityTimeout)
+	}
+	if *m.VisibilityTimeout < time.Second || *m.VisibilityTimeout > maxVisibilityTimeout {
+		return nil, errors.New("invalid value for 'visibilityTimeout': must be between 1s and 7 days")
+	}
+
+	if m.Concurrency < 1 {
+		return nil, errors.New("invalid value for 'concurrency': must be greater than 0")
+	}
+	if m.BatchSize < 1 || m.BatchSize > maxBatchSize {
+		return nil, errors.New("invalid value for 'batchSize': must be between 1 and 32")
+	}
+	if m.StrictOrdering && (m.Concurrency > 1 || m.BatchSize > 1) {
+		return nil, errors.New("'strictOrdering' cannot be used with 'concurrency' or 'batchSize' greater than 1")
+	}
+
+	if m.DeadLetterQueueName != "" {
+		if m.MaxDequeueCount < 1 {
+			return nil, errors.New("invalid value for 'maxDequeueCount': must be greater than 0")
+		}
+		if m.DeadLetterQueueName == m.QueueName {
+			return nil, errors.New("invalid value for 'deadLetterQueueName': must be different from the queue name")
+		}
+	}
 
 	ttl, ok, err := contribMetadata.TryGetTTL(meta.Properties)
 	if err != nil {
@@ -336,10 +623,24 @@ func parseMetadata(meta bindings.Metadata) (*storageQueuesMetadata, error) {
 }
 
 func (a *AzureStorageQueues) Operations() []bindings.OperationKind {
-	return []bindings.OperationKind{bindings.CreateOperation}
+	return []bindings.OperationKind{bindings.CreateOperation, bindings.DeleteOperation, peekOperation}
 }
 
 func (a *AzureStorageQueues) Invoke(ctx context.Context, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	switch req.Operation {
+	// An empty operation is treated as create for backwards-compatibility
+	case bindings.CreateOperation, "":
+		return a.create(ctx, req)
+	case bindings.DeleteOperation:
+		return a.delete(ctx, req)
+	case peekOperation:
+		return a.peek(ctx, req)
+	default:
+		return nil, fmt.Errorf("unsupported operation: %s", req.Operation)
+	}
+}
+
+func (a *AzureStorageQueues) create(ctx context.Context, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
 	ttlToUse := a.metadata.TTL
 	ttl, ok, err := contribMetadata.TryGetTTL(req.Metadata)
 	if err != nil {
@@ -358,6 +659,44 @@ func (a *AzureStorageQueues) Invoke(ctx context.Context, req *bindings.InvokeReq
 	return nil, nil
 }
 
+func (a *AzureStorageQueues) delete(ctx context.Context, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	if req.Metadata[messageID] == "" || req.Metadata[popReceipt] == "" {
+		return nil, fmt.Errorf("the delete operation requires the '%s' and '%s' metadata properties", messageID, popReceipt)
+	}
+
+	err := a.helper.Delete(ctx, req.Metadata[messageID], req.Metadata[popReceipt])
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete message: %w", err)
+	}
+
+	return nil, nil
+}
+
+func (a *AzureStorageQueues) peek(ctx context.Context, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	count := int32(1)
+	if val := req.Metadata[numberOfMessages]; val != "" {
+		n, err := strconv.ParseInt(val, 10, 32)
+		if err != nil || n < 1 || n > maxBatchSize {
+			return nil, fmt.Errorf("invalid value for '%s': must be a number between 1 and %d", numberOfMessages, maxBatchSize)
+		}
+		count = int32(n)
+	}
+
+	msgs, err := a.helper.Peek(ctx, count)
+	if err != nil {
+		return nil, fmt.Errorf("failed to peek messages: %w", err)
+	}
+
+	data, err := json.Marshal(msgs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize messages: %w", err)
+	}
+
+	return &bindings.InvokeResponse{
+		Data: data,
+	}, nil
+}
+
 func (a *AzureStorageQueues) Read(ctx context.Context, handler bindings.Handler) error {
 	if a.closed.Load() {
 		return errors.New("input binding is closed")
@@ -365,11 +704,17 @@ func (a *AzureStorageQueues) Read(ctx context.Context, handler bindings.Handler)
 
 	c := consumer{
 		callback: handler,
+		logger:   a.logger,
+	}
+
+	concurrency := 1
+	if a.metadata != nil && a.metadata.Concurrency > 1 {
+		concurrency = a.metadata.Concurrency
 	}
 
 	// Close read context when binding is closed.
 	readCtx, cancel := context.WithCancel(ctx)
-	a.wg.Add(2)
+	a.wg.Add(1 + concurrency)
 	go func() {
 		defer a.wg.Done()
 		defer cancel()
@@ -379,17 +724,21 @@ func (a *AzureStorageQueues) Read(ctx context.Context, handler bindings.Handler)
 		case <-ctx.Done():
 		}
 	}()
-	go func() {
-		defer a.wg.Done()
-		// Read until context is canceled
-		var err error
-		for readCtx.Err() == nil {
-			err = a.helper.Read(readCtx, &c)
-			if err != nil {
-				a.logger.Errorf("error from c: %s", err)
+
+	// Start the workers, each reading from the queue independently
+	for i := 0; i < concurrency; i++ {
+		go func() {
+			defer a.wg.Done()
+			// Read until context is canceled
+			var err error
+			for readCtx.Err() == nil {
+				err = a.helper.Read(readCtx, &c)
+				if err != nil {
+					a.logger.Errorf("error from c: %s", err)
+				}
 			}
-		}
-	}()
+		}()
+	}
 
 	return nil
 }
@@ -16,7 +16,10 @@ package storagequeues
 import (
 	"context"
 	"encoding/base64"
+	"errors"
+	"net/http"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -25,6 +28,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/dapr/components-contrib/bindings"
+	azauth "github.com/dapr/components-contrib/common/authentication/azure"
 	"github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/kit/logger"
 	"github.com/dapr/kit/ptr"
@@ -79,6 +83,16 @@ func (m *MockHelper) Read(ctx context.Context, consumer *consumer) error {
 	return retvals.Error(0)
 }
 
+func (m *MockHelper) Delete(ctx context.Context, messageID string, popReceipt string) error {
+	retvals := m.Called(messageID, popReceipt)
+	return retvals.Error(0)
+}
+
+func (m *MockHelper) Peek(ctx context.Context, count int32) ([]peekedMessage, error) {
+	retvals := m.Called(count)
+	return retvals.Get(0).([]peekedMessage), retvals.Error(1)
+}
+
 func (m *MockHelper) Close() error {
 	defer m.wg.Wait()
 	close(m.closeCh)
@@ -255,6 +269,86 @@ func TestReadQueueDecode(t *testing.T) {
 	require.NoError(t, a.Close())
 }
 
+func TestReadQueueHandlerPanic(t *testing.T) {
+	fake := newFakeQueueService()
+	props := map[string]string{
+		"queue":              "queue1",
+		"storageAccount":     "devstoreaccount1",
+		"strictOrdering":     "true",
+		"minPollingInterval": "100ms",
+	}
+	d := newTestQueueHelper(t, fake, props)
+	m, err := parseMetadata(bindings.Metadata{Base: metadata.Base{Properties: props}})
+	require.NoError(t, err)
+	a := &AzureStorageQueues{helper: d, metadata: m, logger: logger.NewLogger("test"), closeCh: make(chan struct{})}
+	defer a.Close()
+
+	require.NoError(t, d.Write(context.Background(), []byte("panic"), nil))
+	require.NoError(t, d.Write(context.Background(), []byte("message"), nil))
+
+	// The handler panics the first time it receives the message
+	received := make(chan string, 3)
+	var panicked atomic.Bool
+	err = a.Read(context.Background(), func(ctx context.Context, res *bindings.ReadResponse) ([]byte, error) {
+		s := string(res.Data)
+		received <- s
+		if s == "panic" && panicked.CompareAndSwap(false, true) {
+			panic("simulated panic")
+		}
+		return nil, nil
+	})
+	require.NoError(t, err)
+
+	// The read loop keeps running, and the message is redelivered
+	got := make([]string, 0, 3)
+	for len(got) < 3 {
+		select {
+		case s := <-received:
+			got = append(got, s)
+		case <-time.After(10 * time.Second):
+			t.Fatal("Timeout waiting for messages")
+		}
+	}
+	assert.Equal(t, []string{"panic", "panic", "message"}, got)
+	assert.Eventually(t, func() bool {
+		return len(fake.Messages("queue1")) == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestConsumerInvoke(t *testing.T) {
+	t.Run("returns handler error", func(t *testing.T) {
+		c := consumer{callback: func(ctx context.Context, data *bindings.ReadResponse) ([]byte, error) {
+			return nil, errors.New("simulated error")
+		}}
+		err := c.invoke(context.Background(), &bindings.ReadResponse{})
+		require.Error(t, err)
+		assert.Equal(t, "simulated error", err.Error())
+	})
+
+	t.Run("recovers from panics", func(t *testing.T) {
+		calls := 0
+		c := consumer{
+			callback: func(ctx context.Context, data *bindings.ReadResponse) ([]byte, error) {
+				calls++
+				if calls == 1 {
+					panic("simulated panic")
+				}
+				return nil, nil
+			},
+			logger: logger.NewLogger("test"),
+		}
+
+		err := c.invoke(context.Background(), &bindings.ReadResponse{})
+		require.Error(t, err)
+		require.ErrorContains(t, err, "simulated panic")
+
+		// Subsequent invocations work normally
+		err = c.invoke(context.Background(), &bindings.ReadResponse{})
+		require.NoError(t, err)
+		assert.Equal(t, 2, calls)
+	})
+}
+
 // Uncomment this function to test reding from local queue
 //nolint:godot
 /* func TestReadLocalQueue(t *testing.T) {
@@ -322,64 +416,95 @@ func TestParseMetadata(t *testing.T) {
 		properties map[string]string
 		// Account key is parsed in azauth
 		// expectedAccountKey       string
-		expectedQueueName         string
-		expectedQueueEndpointURL  string
-		expectedPollingInterval   time.Duration
-		expectedTTL               *time.Duration
-		expectedVisibilityTimeout *time.Duration
+		expectedQueueName          string
+		expectedQueueEndpointURL   string
+		expectedPollingInterval    time.Duration
+		expectedMinPollingInterval time.Duration
+		expectedTTL                *time.Duration
+		expectedVisibilityTimeout  *time.Duration
 	}{
 		{
 			name:       "Account and key",
 			properties: map[string]string{"storageAccessKey": "myKey", "queue": "queue1", "storageAccount": "devstoreaccount1"},
 			// expectedAccountKey:       "myKey",
-			expectedQueueName:         "queue1",
-			expectedQueueEndpointURL:  "",
-			expectedPollingInterval:   defaultPollingInterval,
-			expectedVisibilityTimeout: ptr.Of(defaultVisibilityTimeout),
+			expectedQueueName:          "queue1",
+			expectedQueueEndpointURL:   "",
+			expectedPollingInterval:    defaultPollingInterval,
+			expectedMinPollingInterval: defaultMinPollingInterval,
+			expectedVisibilityTimeout:  ptr.Of(defaultVisibilityTimeout),
 		},
 		{
 			name:       "Accout, key, and endpoint",
 			properties: map[string]string{"accountKey": "myKey", "queueName": "queue1", "storageAccount": "someAccount", "queueEndpointUrl": "https://foo.example.com:10001"},
 			// expectedAccountKey:       "myKey",
-			expectedQueueName:         "queue1",
-			expectedQueueEndpointURL:  "https://foo.example.com:10001",
-			expectedPollingInterval:   defaultPollingInterval,
-			expectedVisibilityTimeout: ptr.Of(defaultVisibilityTimeout),
+			expectedQueueName:          "queue1",
+			expectedQueueEndpointURL:   "https://foo.example.com:10001",
+			expectedPollingInterval:    defaultPollingInterval,
+			expectedMinPollingInterval: defaultMinPollingInterval,
+			expectedVisibilityTimeout:  ptr.Of(defaultVisibilityTimeout),
 		},
 		{
 			name:       "Empty TTL",
 			properties: map[string]string{"storageAccessKey": "myKey", "queue": "queue1", "storageAccount": "devstoreaccount1", metadata.TTLMetadataKey: ""},
 			// expectedAccountKey:       "myKey",
-			expectedQueueName:         "queue1",
-			expectedQueueEndpointURL:  "",
-			expectedPollingInterval:   defaultPollingInterval,
-			expectedVisibilityTimeout: ptr.Of(defaultVisibilityTimeout),
+			expectedQueueName:          "queue1",
+			expectedQueueEndpointURL:   "",
+			expectedPollingInterval:    defaultPollingInterval,
+			expectedMinPollingInterval: defaultMinPollingInterval,
+			expectedVisibilityTimeout:  ptr.Of(defaultVisibilityTimeout),
 		},
 		{
 			name:       "With TTL",
 			properties: map[string]string{"accessKey": "myKey", "storageAccountQueue": "queue1", "storageAccount": "devstoreaccount1", metadata.TTLMetadataKey: "1"},
 			// expectedAccountKey:       "myKey",
-			expectedQueueName:         "queue1",
-			expectedTTL:               &oneSecondDuration,
-			expectedQueueEndpointURL:  "",
-			expectedPollingInterval:   defaultPollingInterval,
-			expectedVisibilityTimeout: ptr.Of(defaultVisibilityTimeout),
+			expectedQueueName:          "queue1",
+			expectedTTL:                &oneSecondDuration,
+			expectedQueueEndpointURL:   "",
+			expectedPollingInterval:    defaultPollingInterval,
+			expectedMinPollingInterval: defaultMinPollingInterval,
+			expectedVisibilityTimeout:  ptr.Of(defaultVisibilityTimeout),
 		},
 		{
-			name:                      "With visibility timeout",
-			properties:                map[string]string{"accessKey": "myKey", "storageAccountQueue": "queue1", "storageAccount": "devstoreaccount1", "visibilityTimeout": "5s"},
-			expectedQueueName:         "queue1",
-			expectedPollingInterval:   defaultPollingInterval,
-			expectedVisibilityTimeout: ptr.Of(5 * time.Second),
+			name:                       "With visibility timeout",
+			properties:                 map[string]string{"accessKey": "myKey", "storageAccountQueue": "queue1", "storageAccount": "devstoreaccount1", "visibilityTimeout": "5s"},
+			expectedQueueName:          "queue1",
+			expectedPollingInterval:    defaultPollingInterval,
+			expectedMinPollingInterval: defaultMinPollingInterval,
+			expectedVisibilityTimeout:  ptr.Of(5 * time.Second),
 		},
 		{
 			name:       "With polling interval",
 			properties: map[string]string{"accessKey": "myKey", "storageAccountQueue": "queue1", "storageAccount": "devstoreaccount1", "pollingInterval": "2s"},
 			// expectedAccountKey:       "myKey",
-			expectedQueueName:         "queue1",
-			expectedQueueEndpointURL:  "",
-			expectedPollingInterval:   2 * time.Second,
-			expectedVisibilityTimeout: ptr.Of(defaultVisibilityTimeout),
+			expectedQueueName:          "queue1",
+			expectedQueueEndpointURL:   "",
+			expectedPollingInterval:    2 * time.Second,
+			expectedMinPollingInterval: defaultMinPollingInterval,
+			expectedVisibilityTimeout:  ptr.Of(defaultVisibilityTimeout),
+		},
+		{
+			name:                       "With polling interval lower than default min polling interval",
+			properties:                 map[string]string{"accessKey": "myKey", "storageAccountQueue": "queue1", "storageAccount": "devstoreaccount1", "pollingInterval": "500ms"},
+			expectedQueueName:          "queue1",
+			expectedPollingInterval:    500 * time.Millisecond,
+			expectedMinPollingInterval: 500 * time.Millisecond,
+			expectedVisibilityTimeout:  ptr.Of(defaultVisibilityTimeout),
+		},
+		{
+			name:                       "With polling interval aliases",
+			properties:                 map[string]string{"accessKey": "myKey", "storageAccountQueue": "queue1", "storageAccount": "devstoreaccount1", "pollingIntervalMax": "20s", "pollingIntervalMin": "300ms"},
+			expectedQueueName:          "queue1",
+			expectedPollingInterval:    20 * time.Second,
+			expectedMinPollingInterval: 300 * time.Millisecond,
+			expectedVisibilityTimeout:  ptr.Of(defaultVisibilityTimeout),
+		},
+		{
+			name:                       "With min polling interval",
+			properties:                 map[string]string{"accessKey": "myKey", "storageAccountQueue": "queue1", "storageAccount": "devstoreaccount1", "pollingInterval": "30s", "minPollingInterval": "200ms"},
+			expectedQueueName:          "queue1",
+			expectedPollingInterval:    30 * time.Second,
+			expectedMinPollingInterval: 200 * time.Millisecond,
+			expectedVisibilityTimeout:  ptr.Of(defaultVisibilityTimeout),
 		},
 	}
 
@@ -401,6 +526,8 @@ func TestParseMetadata(t *testing.T) {
 			}
 			assert.Equal(t, tt.expectedQueueEndpointURL, meta.QueueEndpoint)
 			assert.Equal(t, tt.expectedVisibilityTimeout, meta.VisibilityTimeout)
+			assert.Equal(t, tt.expectedPollingInterval, meta.PollingInterval)
+			assert.Equal(t, tt.expectedMinPollingInterval, meta.MinPollingInterval)
 		})
 	}
 
@@ -417,6 +544,76 @@ func TestParseMetadata(t *testing.T) {
 		_, err := parseMetadata(m)
 		require.Error(t, err)
 	})
+
+	t.Run("invalid visibilityTimeout", func(t *testing.T) {
+		for _, v := range []string{"0", "500ms", "-1s", "169h"} {
+			m := bindings.Metadata{Base: metadata.Base{
+				Properties: map[string]string{
+					"accessKey":           "myKey",
+					"storageAccountQueue": "queue1",
+					"storageAccount":      "devstoreaccount1",
+					"visibilityTimeout":   v,
+				},
+			}}
+
+			_, err := parseMetadata(m)
+			require.Errorf(t, err, "expected error for value %s", v)
+		}
+	})
+
+	t.Run("strictOrdering", func(t *testing.T) {
+		m := bindings.Metadata{Base: metadata.Base{
+			Properties: map[string]string{
+				"accessKey":           "myKey",
+				"storageAccountQueue": "queue1",
+				"storageAccount":      "devstoreaccount1",
+				"strictOrdering":      "true",
+			},
+		}}
+
+		meta, err := parseMetadata(m)
+		require.NoError(t, err)
+		assert.True(t, meta.StrictOrdering)
+	})
+
+	t.Run("minPollingInterval greater than pollingInterval", func(t *testing.T) {
+		m := bindings.Metadata{Base: metadata.Base{
+			Properties: map[string]string{
+				"accessKey":           "myKey",
+				"storageAccountQueue": "queue1",
+				"storageAccount":      "devstoreaccount1",
+				"pollingInterval":     "2s",
+				"minPollingInterval":  "5s",
+			},
+		}}
+
+		_, err := parseMetadata(m)
+		require.Error(t, err)
+	})
+}
+
+func TestNextPollingInterval(t *testing.T) {
+	d := &AzureQueueHelper{
+		pollingInterval:        10 * time.Second,
+		minPollingInterval:     time.Second,
+		currentPollingInterval: time.Second,
+	}
+
+	// Starts at the minimum and grows while the queue is empty, up to the maximum
+	assert.Equal(t, time.Second, d.nextPollingInterval(true))
+	assert.Equal(t, 2*time.Second, d.nextPollingInterval(true))
+	assert.Equal(t, 4*time.Second, d.nextPollingInterval(true))
+	assert.Equal(t, 8*time.Second, d.nextPollingInterval(true))
+	assert.Equal(t, 10*time.Second, d.nextPollingInterval(true))
+	assert.Equal(t, 10*time.Second, d.nextPollingInterval(true))
+
+	// Resets to the minimum as soon as messages are received
+	assert.Equal(t, time.Second, d.nextPollingInterval(false))
+	assert.Equal(t, time.Second, d.nextPollingInterval(false))
+
+	// The first wait after the queue is found empty again is the minimum
+	assert.Equal(t, time.Second, d.nextPollingInterval(true))
+	assert.Equal(t, 2*time.Second, d.nextPollingInterval(true))
 }
 
 func TestParseMetadataWithInvalidTTL(t *testing.T) {
@@ -448,3 +645,530 @@ func TestParseMetadataWithInvalidTTL(t *testing.T) {
 		})
 	}
 }
+
+// Returns an AzureQueueHelper that uses the fake queue service.
+func newTestQueueHelper(t *testing.T, fake *fakeQueueService, props map[string]string) *AzureQueueHelper {
+	t.Helper()
+
+	properties := map[string]string{
+		"queue":          "queue1",
+		"storageAccount": "devstoreaccount1",
+	}
+	for k, v := range props {
+		properties[k] = v
+	}
+	m, err := parseMetadata(bindings.Metadata{Base: metadata.Base{Properties: properties}})
+	require.NoError(t, err)
+
+	d := NewAzureQueueHelper(logger.NewLogger("test")).(*AzureQueueHelper)
+	err = d.initWithClient(context.Background(), m, fake.NewServiceClient(t))
+	require.NoError(t, err)
+	return d
+}
+
+func TestReadVisibilityTimeout(t *testing.T) {
+	fake := newFakeQueueService()
+	d := newTestQueueHelper(t, fake, map[string]string{
+		"visibilityTimeout": "2m",
+	})
+	require.NoError(t, d.Write(context.Background(), []byte("message"), nil))
+
+	received := 0
+	c := &consumer{callback: func(ctx context.Context, res *bindings.ReadResponse) ([]byte, error) {
+		received++
+		assert.Equal(t, "message", string(res.Data))
+		return nil, nil
+	}}
+	require.NoError(t, d.Read(context.Background(), c))
+	assert.Equal(t, 1, received)
+
+	dequeues := fake.Requests(http.MethodGet, "queue1/messages")
+	require.Len(t, dequeues, 1)
+	assert.Equal(t, "120", dequeues[0].Query.Get("visibilitytimeout"))
+
+	// The message has been deleted
+	assert.Empty(t, fake.Messages("queue1"))
+}
+
+func TestReadDeadLetterQueue(t *testing.T) {
+	fake := newFakeQueueService()
+	d := newTestQueueHelper(t, fake, map[string]string{
+		"deadLetterQueueName": "queue1-dlq",
+		"maxDequeueCount":     "3",
+	})
+	require.NoError(t, d.Write(context.Background(), []byte("message"), nil))
+
+	received := 0
+	c := &consumer{callback: func(ctx context.Context, res *bindings.ReadResponse) ([]byte, error) {
+		received++
+		return nil, errors.New("handler error")
+	}}
+
+	// The handler is invoked up to maxDequeueCount times
+	for i := 0; i < 3; i++ {
+		require.Error(t, d.Read(context.Background(), c))
+		fake.MakeVisible("queue1")
+	}
+	assert.Equal(t, 3, received)
+	assert.Equal(t, []string{"message"}, fake.Messages("queue1"))
+	assert.Empty(t, fake.Messages("queue1-dlq"))
+
+	// The next time, the message is moved to the dead-letter queue
+	require.NoError(t, d.Read(context.Background(), c))
+	assert.Equal(t, 3, received)
+	assert.Empty(t, fake.Messages("queue1"))
+	assert.Equal(t, []string{"message"}, fake.Messages("queue1-dlq"))
+
+	t.Run("invalid configuration", func(t *testing.T) {
+		for _, props := range []map[string]string{
+			{"deadLetterQueueName": "queue1-dlq", "maxDequeueCount": "0"},
+			{"deadLetterQueueName": "queue1"},
+		} {
+			props["queue"] = "queue1"
+			props["storageAccount"] = "devstoreaccount1"
+			_, err := parseMetadata(bindings.Metadata{Base: metadata.Base{Properties: props}})
+			require.Error(t, err)
+		}
+	})
+}
+
+func TestReadStrictOrdering(t *testing.T) {
+	// Reads from the queue and returns the received messages; the first delivery of "message1" fails
+	readAll := func(t *testing.T, props map[string]string) []string {
+		fake := newFakeQueueService()
+		props["minPollingInterval"] = "100ms"
+		d := newTestQueueHelper(t, fake, props)
+		require.NoError(t, d.Write(context.Background(), []byte("message1"), nil))
+		require.NoError(t, d.Write(context.Background(), []byte("message2"), nil))
+
+		received := []string{}
+		failed := false
+		c := &consumer{callback: func(ctx context.Context, res *bindings.ReadResponse) ([]byte, error) {
+			received = append(received, string(res.Data))
+			if string(res.Data) == "message1" && !failed {
+				failed = true
+				return nil, errors.New("handler error")
+			}
+			return nil, nil
+		}}
+
+		require.Error(t, d.Read(context.Background(), c))
+		require.NoError(t, d.Read(context.Background(), c))
+		require.NoError(t, d.Read(context.Background(), c))
+		return received
+	}
+
+	t.Run("failed message is redelivered before the next one", func(t *testing.T) {
+		received := readAll(t, map[string]string{
+			"strictOrdering": "true",
+		})
+		assert.Equal(t, []string{"message1", "message1", "message2"}, received)
+	})
+
+	t.Run("without strict ordering the failed message waits for the visibility timeout", func(t *testing.T) {
+		received := readAll(t, map[string]string{
+			"visibilityTimeout": "1m",
+		})
+		assert.Equal(t, []string{"message1", "message2"}, received)
+	})
+
+	t.Run("failed message is made visible immediately", func(t *testing.T) {
+		fake := newFakeQueueService()
+		d := newTestQueueHelper(t, fake, map[string]string{
+			"strictOrdering":     "true",
+			"minPollingInterval": "100ms",
+		})
+		require.NoError(t, d.Write(context.Background(), []byte("message"), nil))
+
+		c := &consumer{callback: func(ctx context.Context, res *bindings.ReadResponse) ([]byte, error) {
+			return nil, errors.New("handler error")
+		}}
+		require.Error(t, d.Read(context.Background(), c))
+
+		updates := fake.Requests(http.MethodPut, "queue1/messages/"+fake.queues["queue1"][0].ID)
+		require.Len(t, updates, 1)
+		assert.Equal(t, "0", updates[0].Query.Get("visibilitytimeout"))
+		assert.False(t, fake.queues["queue1"][0].VisibleAt.After(time.Now()))
+	})
+}
+
+func TestReadConcurrency(t *testing.T) {
+	const numMessages = 10
+
+	// Processes all messages, and returns the maximum number of handler invocations that were in flight at the same time, and the number of messages returned by each dequeue request
+	processAll := func(t *testing.T, props map[string]string, concurrency int32) (int32, []int) {
+		fake := newFakeQueueService()
+		d := newTestQueueHelper(t, fake, props)
+		for i := 0; i < numMessages; i++ {
+			require.NoError(t, d.Write(context.Background(), []byte("message"), nil))
+		}
+
+		props["queue"] = "queue1"
+		props["storageAccount"] = "devstoreaccount1"
+		m, err := parseMetadata(bindings.Metadata{Base: metadata.Base{Properties: props}})
+		require.NoError(t, err)
+		a := &AzureStorageQueues{helper: d, metadata: m, logger: logger.NewLogger("test"), closeCh: make(chan struct{})}
+		defer a.Close()
+
+		// Handlers are blocked until the expected number of invocations is in flight, so they overlap regardless of timing
+		// If that never happens, they are released after a timeout, and the test fails when checking the peak
+		var inFlight, peak, received atomic.Int32
+		release := make(chan struct{})
+		var releaseOnce sync.Once
+		done := make(chan struct{})
+		err = a.Read(context.Background(), func(ctx context.Context, res *bindings.ReadResponse) ([]byte, error) {
+			n := inFlight.Add(1)
+			defer inFlight.Add(-1)
+			for {
+				cur := peak.Load()
+				if n <= cur || peak.CompareAndSwap(cur, n) {
+					break
+				}
+			}
+			if n >= concurrency {
+				releaseOnce.Do(func() {
+					close(release)
+				})
+			}
+
+			select {
+			case <-release:
+			case <-time.After(2 * time.Second):
+			}
+
+			if received.Add(1) == numMessages {
+				close(done)
+			}
+			return nil, nil
+		})
+		require.NoError(t, err)
+
+		select {
+		case <-done:
+		case <-time.After(10 * time.Second):
+			t.Fatal("timed out waiting for messages")
+		}
+
+		assert.Eventually(t, func() bool {
+			return len(fake.Messages("queue1")) == 0
+		}, time.Second, 10*time.Millisecond)
+
+		var returned []int
+		for _, req := range fake.Requests(http.MethodGet, "queue1/messages") {
+			if req.Returned > 0 {
+				returned = append(returned, req.Returned)
+			}
+		}
+		return peak.Load(), returned
+	}
+
+	t.Run("sequential", func(t *testing.T) {
+		peak, returned := processAll(t, map[string]string{}, 1)
+		assert.Equal(t, int32(1), peak)
+		for _, n := range returned {
+			assert.Equal(t, 1, n)
+		}
+	})
+
+	t.Run("concurrent", func(t *testing.T) {
+		peak, returned := processAll(t, map[string]string{"concurrency": "5"}, 5)
+		assert.Greater(t, peak, int32(1))
+		assert.LessOrEqual(t, peak, int32(5))
+		for _, n := range returned {
+			assert.Equal(t, 1, n)
+		}
+	})
+
+	t.Run("batched", func(t *testing.T) {
+		peak, returned := processAll(t, map[string]string{"concurrency": "5", "batchSize": "2"}, 5)
+		assert.Greater(t, peak, int32(1))
+		assert.LessOrEqual(t, peak, int32(5))
+		assert.Contains(t, returned, 2)
+		for _, n := range returned {
+			assert.LessOrEqual(t, n, 2)
+		}
+	})
+
+	t.Run("invalid configuration", func(t *testing.T) {
+		for _, props := range []map[string]string{
+			{"concurrency": "0"},
+			{"batchSize": "0"},
+			{"batchSize": "33"},
+			{"strictOrdering": "true", "concurrency": "2"},
+			{"strictOrdering": "true", "batchSize": "2"},
+		} {
+			props["queue"] = "queue1"
+			props["storageAccount"] = "devstoreaccount1"
+			_, err := parseMetadata(bindings.Metadata{Base: metadata.Base{Properties: props}})
+			require.Errorf(t, err, "expected error for %v", props)
+		}
+	})
+}
+
+func TestReadEmptyQueueCancellation(t *testing.T) {
+	fake := newFakeQueueService()
+	d := newTestQueueHelper(t, fake, map[string]string{
+		"pollingInterval":    "1m",
+		"minPollingInterval": "30s",
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	start := time.Now()
+	c := &consumer{callback: func(ctx context.Context, res *bindings.ReadResponse) ([]byte, error) {
+		return nil, nil
+	}}
+	require.NoError(t, d.Read(ctx, c))
+	assert.Less(t, time.Since(start), 5*time.Second)
+
+	// The polling interval has grown
+	assert.Equal(t, time.Minute, d.currentPollingInterval)
+}
+
+func TestReadResetsPollingInterval(t *testing.T) {
+	fake := newFakeQueueService()
+	d := newTestQueueHelper(t, fake, map[string]string{
+		"pollingInterval":    "1m",
+		"minPollingInterval": "1s",
+	})
+
+	// Grow the polling interval as if the queue had been empty for a while
+	for i := 0; i < 10; i++ {
+		d.nextPollingInterval(true)
+	}
+	require.Equal(t, time.Minute, d.currentPollingInterval)
+
+	// A single read that returns messages resets the interval to the minimum
+	require.NoError(t, d.Write(context.Background(), []byte("message"), nil))
+	c := &consumer{callback: func(ctx context.Context, res *bindings.ReadResponse) ([]byte, error) {
+		return nil, nil
+	}}
+	require.NoError(t, d.Read(context.Background(), c))
+	assert.Empty(t, fake.Messages("queue1"))
+	assert.Equal(t, time.Second, d.currentPollingInterval)
+}
+
+func TestInvokeOperations(t *testing.T) {
+	newBinding := func(t *testing.T, mm *MockHelper) *AzureStorageQueues {
+		a := &AzureStorageQueues{helper: mm, logger: logger.NewLogger("test"), closeCh: make(chan struct{})}
+		err := a.Init(context.Background(), bindings.Metadata{Base: metadata.Base{Properties: map[string]string{
+			"queue":          "queue1",
+			"storageAccount": "devstoreaccount1",
+		}}})
+		require.NoError(t, err)
+		return a
+	}
+
+	t.Run("delete", func(t *testing.T) {
+		mm := new(MockHelper)
+		mm.On("Delete", "id1", "receipt1").Return(nil)
+		a := newBinding(t, mm)
+
+		_, err := a.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: bindings.DeleteOperation,
+			Metadata:  map[string]string{messageID: "id1", popReceipt: "receipt1"},
+		})
+		require.NoError(t, err)
+		mm.AssertExpectations(t)
+
+		// Message ID and pop receipt are required
+		_, err = a.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: bindings.DeleteOperation,
+			Metadata:  map[string]string{messageID: "id1"},
+		})
+		require.Error(t, err)
+	})
+
+	t.Run("peek", func(t *testing.T) {
+		mm := new(MockHelper)
+		mm.On("Peek", int32(5)).Return([]peekedMessage{
+			{MessageID: "id1", DequeueCount: 2, MessageText: "hello"},
+		}, nil)
+		a := newBinding(t, mm)
+
+		res, err := a.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: peekOperation,
+			Metadata:  map[string]string{numberOfMessages: "5"},
+		})
+		require.NoError(t, err)
+		require.NotNil(t, res)
+		assert.JSONEq(t, `[{"messageID":"id1","dequeueCount":2,"messageText":"hello"}]`, string(res.Data))
+		mm.AssertExpectations(t)
+
+		_, err = a.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: peekOperation,
+			Metadata:  map[string]string{numberOfMessages: "100"},
+		})
+		require.Error(t, err)
+	})
+
+	t.Run("unsupported operation", func(t *testing.T) {
+		a := newBinding(t, new(MockHelper))
+
+		_, err := a.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: bindings.GetOperation,
+		})
+		require.ErrorContains(t, err, "unsupported operation")
+	})
+}
+
+func TestDeleteAndPeek(t *testing.T) {
+	fake := newFakeQueueService()
+	d := newTestQueueHelper(t, fake, nil)
+	require.NoError(t, d.Write(context.Background(), []byte("message1"), nil))
+	require.NoError(t, d.Write(context.Background(), []byte("message2"), nil))
+
+	// Peek doesn't change the visibility of messages
+	msgs, err := d.Peek(context.Background(), 5)
+	require.NoError(t, err)
+	require.Len(t, msgs, 2)
+	assert.Equal(t, "message1", msgs[0].MessageText)
+	assert.Equal(t, "message2", msgs[1].MessageText)
+	assert.NotEmpty(t, msgs[0].MessageID)
+	assert.NotNil(t, msgs[0].InsertionTime)
+
+	// Receive a message, then delete it using its ID and pop receipt
+	var msgID, msgPopReceipt string
+	c := &consumer{callback: func(ctx context.Context, res *bindings.ReadResponse) ([]byte, error) {
+		msgID = res.Metadata[messageID]
+		msgPopReceipt = res.Metadata[popReceipt]
+		return nil, errors.New("not processed")
+	}}
+	require.Error(t, d.Read(context.Background(), c))
+	require.NoError(t, d.Delete(context.Background(), msgID, msgPopReceipt))
+	assert.Equal(t, []string{"message2"}, fake.Messages("queue1"))
+
+	msgs, err = d.Peek(context.Background(), 5)
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, "message2", msgs[0].MessageText)
+}
+
+func TestAuthMethodSelection(t *testing.T) {
+	parse := func(props map[string]string) (*storageQueuesMetadata, error) {
+		props["queue"] = "queue1"
+		props["storageAccount"] = "devstoreaccount1"
+		return parseMetadata(bindings.Metadata{Base: metadata.Base{Properties: props}})
+	}
+
+	t.Run("account key", func(t *testing.T) {
+		m, err := parse(map[string]string{"accountKey": "myKey"})
+		require.NoError(t, err)
+		assert.True(t, m.useSharedKey())
+	})
+
+	t.Run("service principal", func(t *testing.T) {
+		m, err := parse(map[string]string{"azureClientId": "id", "azureClientSecret": "secret", "azureTenantId": "tenant"})
+		require.NoError(t, err)
+		assert.False(t, m.useSharedKey())
+	})
+
+	t.Run("managed identity", func(t *testing.T) {
+		m, err := parse(map[string]string{})
+		require.NoError(t, err)
+		assert.False(t, m.useSharedKey())
+	})
+
+	t.Run("account key and service principal", func(t *testing.T) {
+		_, err := parse(map[string]string{"accountKey": "myKey", "azureClientSecret": "secret"})
+		require.ErrorContains(t, err, "only one authentication method")
+
+		_, err = parse(map[string]string{"accountKey": "myKey", "azureCertificateFile": "/path/to/cert.pem"})
+		require.ErrorContains(t, err, "only one authentication method")
+	})
+}
+
+func TestGetQueueURL(t *testing.T) {
+	testCases := []struct {
+		name     string
+		props    map[string]string
+		expected string
+	}{
+		{
+			name:     "Azure public cloud",
+			props:    map[string]string{},
+			expected: "https://devstoreaccount1.queue.core.windows.net/",
+		},
+		{
+			name:     "Azure China",
+			props:    map[string]string{"azureEnvironment": "AzureChinaCloud"},
+			expected: "https://devstoreaccount1.queue.core.chinacloudapi.cn/",
+		},
+		{
+			name:     "Azure US Government",
+			props:    map[string]string{"azureEnvironment": "AzureUSGovernmentCloud"},
+			expected: "https://devstoreaccount1.queue.core.usgovcloudapi.net/",
+		},
+		{
+			name:     "Custom endpoint takes precedence",
+			props:    map[string]string{"azureEnvironment": "AzureChinaCloud", "queueEndpointUrl": "http://127.0.0.1:10001"},
+			expected: "http://127.0.0.1:10001/devstoreaccount1/",
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.props["queue"] = "queue1"
+			tt.props["storageAccount"] = "devstoreaccount1"
+
+			m, err := parseMetadata(bindings.Metadata{Base: metadata.Base{Properties: tt.props}})
+			require.NoError(t, err)
+			azEnvSettings, err := azauth.NewEnvironmentSettings(tt.props)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.expected, m.GetQueueURL(azEnvSettings))
+		})
+	}
+}
+
+func TestReadResponseMetadata(t *testing.T) {
+	fake := newFakeQueueService()
+	d := newTestQueueHelper(t, fake, nil)
+	require.NoError(t, d.Write(context.Background(), []byte("message"), nil))
+
+	var md map[string]string
+	c := &consumer{callback: func(ctx context.Context, res *bindings.ReadResponse) ([]byte, error) {
+		md = res.Metadata
+		return nil, nil
+	}}
+	require.NoError(t, d.Read(context.Background(), c))
+
+	require.NotNil(t, md)
+	assert.NotEmpty(t, md[messageID])
+	assert.NotEmpty(t, md[popReceipt])
+	assert.Equal(t, "1", md[dequeueCount])
+	for _, key := range []string{insertionTime, expirationTime, nextVisibleTime} {
+		_, err := time.Parse(time.RFC3339, md[key])
+		require.NoErrorf(t, err, "invalid value for %s: %q", key, md[key])
+	}
+
+	inserted, _ := time.Parse(time.RFC3339, md[insertionTime])
+	expires, _ := time.Parse(time.RFC3339, md[expirationTime])
+	assert.Equal(t, defaultTTL, expires.Sub(inserted))
+}
+
+func TestBase64RoundTrip(t *testing.T) {
+	// Binary data that is not valid UTF-8 and that is wrapped in quotes
+	data := []byte{'"', 0xff, 0x00, 0xfe, '\\', 'n', '"'}
+
+	fake := newFakeQueueService()
+	d := newTestQueueHelper(t, fake, map[string]string{
+		"encodeBase64": "true",
+		"decodeBase64": "true",
+	})
+	require.NoError(t, d.Write(context.Background(), data, nil))
+
+	var received []byte
+	c := &consumer{callback: func(ctx context.Context, res *bindings.ReadResponse) ([]byte, error) {
+		received = res.Data
+		return nil, nil
+	}}
+	require.NoError(t, d.Read(context.Background(), c))
+	assert.Equal(t, data, received)
+
+	// JSON strings are still unquoted
+	require.NoError(t, d.Write(context.Background(), []byte(`"hello world"`), nil))
+	require.NoError(t, d.Read(context.Background(), c))
+	assert.Equal(t, "hello world", string(received))
+}
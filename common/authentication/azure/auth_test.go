@@ -97,6 +97,12 @@ func TestEndpointSuffix(t *testing.T) {
 
 	es.Cloud = &cloud.AzureGovernment
 	assert.Equal(t, "vault.usgovcloudapi.net", es.EndpointSuffix(ServiceAzureKeyVault))
+
+	es.Cloud = nil
+	assert.Equal(t, "managedhsm.azure.net", es.EndpointSuffix(ServiceAzureManagedHSM))
+
+	es.Cloud = &cloud.AzureChina
+	assert.Equal(t, "managedhsm.azure.cn", es.EndpointSuffix(ServiceAzureManagedHSM))
 }
 
 //nolint:gosec
@@ -22,6 +22,8 @@ type azureService string
 var (
 	ServiceAzureStorage  azureService = "azurestorage"
 	ServiceAzureKeyVault azureService = "azurekeyvault"
+	// Azure Key Vault Managed HSM
+	ServiceAzureManagedHSM azureService = "azuremanagedhsm"
 )
 
 // EndpointSuffix returns the suffix for the endpoint depending on the cloud used.
@@ -34,6 +36,8 @@ func (s EnvironmentSettings) EndpointSuffix(service azureService) string {
 			return "core.windows.net"
 		case ServiceAzureKeyVault:
 			return "vault.azure.net"
+		case ServiceAzureManagedHSM:
+			return "managedhsm.azure.net"
 		}
 		panic("Invalid service: " + service)
 	case &cloud.AzureChina:
@@ -42,6 +46,8 @@ func (s EnvironmentSettings) EndpointSuffix(service azureService) string {
 			return "core.chinacloudapi.cn"
 		case ServiceAzureKeyVault:
 			return "vault.azure.cn"
+		case ServiceAzureManagedHSM:
+			return "managedhsm.azure.cn"
 		}
 		panic("Invalid service: " + service)
 	case &cloud.AzureGovernment:
@@ -50,6 +56,8 @@ func (s EnvironmentSettings) EndpointSuffix(service azureService) string {
 			return "core.usgovcloudapi.net"
 		case ServiceAzureKeyVault:
 			return "vault.usgovcloudapi.net"
+		case ServiceAzureManagedHSM:
+			return "managedhsm.usgovcloudapi.net"
 		}
 		panic("Invalid service: " + service)
 	}
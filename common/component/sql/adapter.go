@@ -56,6 +56,7 @@ type PgxConn interface {
 // DatabaseConn is the interface matched by all adapters.
 type DatabaseConn interface {
 	Begin(context.Context) (databaseConnTx, error)
+	Query(context.Context, string, ...any) (databaseConnRows, error)
 	QueryRow(context.Context, string, ...any) databaseConnRow
 	Exec(context.Context, string, ...any) (int64, error)
 	IsNoRowsError(err error) bool
@@ -65,6 +66,13 @@ type databaseConnRow interface {
 	Scan(...any) error
 }
 
+type databaseConnRows interface {
+	Next() bool
+	Scan(...any) error
+	Err() error
+	Close()
+}
+
 type databaseConnTx interface {
 	Commit(context.Context) error
 	Rollback(context.Context) error
@@ -94,6 +102,14 @@ func (sqla *DatabaseSQLAdapter) Exec(ctx context.Context, query string, args ...
 	return res.RowsAffected()
 }
 
+func (sqla *DatabaseSQLAdapter) Query(ctx context.Context, query string, args ...any) (databaseConnRows, error) {
+	rows, err := sqla.conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return &databaseSQLRowsAdapter{rows}, nil
+}
+
 func (sqla *DatabaseSQLAdapter) QueryRow(ctx context.Context, query string, args ...any) databaseConnRow {
 	return sqla.conn.QueryRowContext(ctx, query, args...)
 }
@@ -102,6 +118,15 @@ func (sqla *DatabaseSQLAdapter) IsNoRowsError(err error) bool {
 	return errors.Is(err, sql.ErrNoRows)
 }
 
+type databaseSQLRowsAdapter struct {
+	*sql.Rows
+}
+
+func (sqlrows *databaseSQLRowsAdapter) Close() {
+	// Errors that happen while iterating are returned by Err
+	_ = sqlrows.Rows.Close()
+}
+
 type databaseSQLTxAdapter struct {
 	tx *sql.Tx
 }
@@ -148,6 +173,10 @@ func (pga *PgxAdapter) Exec(ctx context.Context, query string, args ...any) (int
 	return res.RowsAffected(), nil
 }
 
+func (pga *PgxAdapter) Query(ctx context.Context, query string, args ...any) (databaseConnRows, error) {
+	return pga.conn.Query(ctx, query, args...)
+}
+
 func (pga *PgxAdapter) QueryRow(ctx context.Context, query string, args ...any) databaseConnRow {
 	return pga.conn.QueryRow(ctx, query, args...)
 }
@@ -0,0 +1,408 @@
+/*
+Copyright 2024 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package nameresolver contains the database-agnostic logic for name resolvers backed by a SQL database.
+// Each database provides the queries to use, by implementing the Queries interface.
+package nameresolver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/google/uuid"
+
+	commonsql "github.com/dapr/components-contrib/common/component/sql"
+	"github.com/dapr/components-contrib/nameresolution"
+	"github.com/dapr/kit/logger"
+)
+
+// ErrNoHost is returned by ResolveID when no host can be found.
+var ErrNoHost = errors.New("no host found with the given ID")
+
+// ErrRegistrationLost is returned when renewing the registration of a host that was removed or taken over by another host.
+var ErrRegistrationLost = errors.New("host registration lost")
+
+// Strategy used by ResolveID to pick an address when there are multiple addresses for an app ID.
+type Strategy string
+
+const (
+	// StrategyRandom picks an address at random, giving less weight to addresses that recently failed requests.
+	StrategyRandom Strategy = "random"
+	// StrategyRoundRobin cycles through the addresses, in order.
+	StrategyRoundRobin Strategy = "roundRobin"
+)
+
+// Queries is the interface implemented by each database to provide the queries used by the name resolver.
+type Queries interface {
+	// RegisterHost returns the query that registers a host, taking over any existing registration for the same address.
+	// The query receives the registration ID, address, app ID, and namespace as parameters.
+	RegisterHost() string
+	// RenewRegistration returns the query that updates the last update time of a registration.
+	// The query receives the registration ID and address as parameters, and must not change any row if the registration doesn't exist.
+	RenewRegistration() string
+	// ResolveID returns the query that selects all non-expired addresses for an app ID.
+	// The query receives the app ID as parameter, and returns one row for each address, with a single column.
+	ResolveID() string
+	// LookupByAddress returns the query that selects the app IDs registered at an address, including expired registrations.
+	// The query receives as parameters the address, and a LIKE pattern (with a backslash as escape character) that matches other addresses; it returns one row for each distinct app ID, with a single column.
+	LookupByAddress() string
+	// DeregisterHost returns the query that removes the registration of a host.
+	// The query receives the registration ID and address as parameters.
+	DeregisterHost() string
+	// UpdateLastCleanup returns the query used by the garbage collector to update the last cleanup time.
+	// See commonsql.GCOptions.UpdateLastCleanupQuery for details.
+	UpdateLastCleanup(arg any) (string, any)
+	// DeleteExpired returns the query used by the garbage collector to delete expired registrations.
+	DeleteExpired() string
+}
+
+// Options contains the options for the Resolver.
+type Options struct {
+	Logger logger.Logger
+
+	// Database connection.
+	// Must be adapted using commonsql.AdaptDatabaseSQLConn or commonsql.AdaptPgxConn.
+	DB commonsql.DatabaseConn
+
+	// Queries for the database.
+	Queries Queries
+
+	// App ID, namespace, and address of the current host.
+	AppID     string
+	Namespace string
+	Address   string
+
+	// Interval for renewing the host's registration.
+	UpdateInterval time.Duration
+	// Interval for running the garbage collector; set to 0 to disable it.
+	CleanupInterval time.Duration
+	// Timeout for database operations.
+	Timeout time.Duration
+	// Strategy for picking an address when there are multiple ones for an app ID.
+	// Defaults to StrategyRandom.
+	Strategy Strategy
+}
+
+// Resolver implements the registration, renewal, resolution, and cleanup logic of name resolvers backed by a SQL database.
+type Resolver struct {
+	opts           Options
+	healthScores   *nameresolution.HealthScores
+	gc             commonsql.GarbageCollector
+	registrationID string
+	rrCounters     map[string]uint64
+	rrLock         sync.Mutex
+	closed         atomic.Bool
+	closeCh        chan struct{}
+	wg             sync.WaitGroup
+}
+
+// New returns a new Resolver.
+// The resolver must be started with Start.
+func New(opts Options) *Resolver {
+	return &Resolver{
+		opts:         opts,
+		healthScores: nameresolution.NewHealthScores(),
+		rrCounters:   make(map[string]uint64),
+		closeCh:      make(chan struct{}),
+	}
+}
+
+// Start the resolver: schedules the garbage collector, registers the host, and begins renewing the registration in background.
+func (r *Resolver) Start(ctx context.Context) error {
+	if r.closed.Load() {
+		return errors.New("resolver is closed")
+	}
+
+	// Init the background GC
+	err := r.initGC()
+	if err != nil {
+		return err
+	}
+
+	// Register the host and update in background
+	err = r.registerHost(ctx)
+	if err != nil {
+		return err
+	}
+
+	r.wg.Add(1)
+	go r.renewRegistration()
+
+	return nil
+}
+
+func (r *Resolver) initGC() (err error) {
+	r.gc, err = commonsql.ScheduleGarbageCollector(commonsql.GCOptions{
+		Logger:                   r.opts.Logger,
+		UpdateLastCleanupQuery:   r.opts.Queries.UpdateLastCleanup,
+		DeleteExpiredValuesQuery: r.opts.Queries.DeleteExpired(),
+		CleanupInterval:          r.opts.CleanupInterval,
+		DB:                       r.opts.DB,
+	})
+	return err
+}
+
+// Registers the host
+func (r *Resolver) registerHost(ctx context.Context) error {
+	// Get the registration ID
+	u, err := uuid.NewRandom()
+	if err != nil {
+		return fmt.Errorf("failed to generate registration ID: %w", err)
+	}
+	r.registrationID = u.String()
+
+	queryCtx, queryCancel := context.WithTimeout(ctx, r.opts.Timeout)
+	defer queryCancel()
+
+	_, err = r.opts.DB.Exec(queryCtx, r.opts.Queries.RegisterHost(),
+		r.registrationID, r.opts.Address, r.opts.AppID, r.opts.Namespace,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to register host: %w", err)
+	}
+
+	return nil
+}
+
+// In backgrounds, periodically renews the host's registration
+// Should be invoked in a background goroutine
+func (r *Resolver) renewRegistration() {
+	defer r.wg.Done()
+
+	addr := r.opts.Address
+
+	// Update every UpdateInterval - Timeout (+ 1 second buffer)
+	// This is because the record has to be updated every UpdateInterval, but we allow up to "timeout" for it to be performed
+	d := r.opts.UpdateInterval - r.opts.Timeout - 1
+	r.opts.Logger.Debugf("Started renewing host registration in background with interval %v", r.opts.UpdateInterval)
+	t := time.NewTicker(d)
+	defer t.Stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	for {
+		select {
+		case <-r.closeCh:
+			// Component is closing
+			r.opts.Logger.Debug("Stopped renewing host registration: component is closing")
+			return
+
+		case <-t.C:
+			// Renew on the ticker
+			r.wg.Add(1)
+			go func() {
+				defer r.wg.Done()
+				err := r.RenewRegistration(ctx, addr)
+				if err != nil {
+					// Log errors
+					r.opts.Logger.Errorf("Failed to update host registration: %v", err)
+
+					if errors.Is(err, ErrRegistrationLost) {
+						// This means that our registration has been taken over by another host
+						// It should never happen unless there's something really bad going on
+						// Panicking here to force a restart of Dapr
+						r.opts.Logger.Fatalf("Host registration lost")
+					}
+				}
+			}()
+		}
+	}
+}
+
+// RenewRegistration renews the registration of the host with the given address.
+// Returns ErrRegistrationLost if the registration doesn't exist anymore.
+func (r *Resolver) RenewRegistration(ctx context.Context, addr string) error {
+	// We retry this query in case of database error, up to the timeout
+	queryCtx, queryCancel := context.WithTimeout(ctx, r.opts.Timeout)
+	defer queryCancel()
+
+	query := r.opts.Queries.RenewRegistration()
+
+	b := backoff.WithContext(backoff.NewConstantBackOff(50*time.Millisecond), queryCtx)
+	return backoff.Retry(func() error {
+		n, err := r.opts.DB.Exec(queryCtx, query, r.registrationID, addr)
+		if err != nil {
+			return fmt.Errorf("database error: %w", err)
+		}
+
+		if n == 0 {
+			// This is a permanent error
+			return backoff.Permanent(ErrRegistrationLost)
+		}
+
+		return nil
+	}, b)
+}
+
+// ResolveID resolves name to address.
+// When there are multiple addresses for the app ID, one is picked according to the configured strategy.
+// With StrategyRandom, addresses that recently failed requests (as reported with ReportResult) are given less weight.
+func (r *Resolver) ResolveID(ctx context.Context, req nameresolution.ResolveRequest) (addr string, err error) {
+	addrs, err := r.resolveAddresses(ctx, req.ID)
+	if err != nil {
+		return "", err
+	}
+	if len(addrs) == 0 {
+		return "", ErrNoHost
+	}
+
+	if r.opts.Strategy == StrategyRoundRobin {
+		return r.pickRoundRobin(req.ID, addrs), nil
+	}
+	return r.healthScores.Pick(req.ID, addrs), nil
+}
+
+// Picks the next address for the app ID, cycling through the addresses sorted alphabetically.
+func (r *Resolver) pickRoundRobin(appID string, addrs nameresolution.AddressList) string {
+	// Sort the addresses so the order is stable regardless of the order rows are returned by the database
+	slices.Sort(addrs)
+
+	r.rrLock.Lock()
+	n := r.rrCounters[appID]
+	r.rrCounters[appID] = n + 1
+	r.rrLock.Unlock()
+
+	return addrs[n%uint64(len(addrs))]
+}
+
+// ReportResult records whether a request sent to an address succeeded, which is used when picking addresses in ResolveID.
+// Implements the nameresolution.ResolverResultReporter interface.
+func (r *Resolver) ReportResult(appID string, address string, success bool) {
+	r.healthScores.ReportResult(appID, address, success)
+}
+
+// Returns all non-expired addresses for the app ID.
+func (r *Resolver) resolveAddresses(ctx context.Context, appID string) (nameresolution.AddressList, error) {
+	queryCtx, queryCancel := context.WithTimeout(ctx, r.opts.Timeout)
+	defer queryCancel()
+
+	rows, err := r.opts.DB.Query(queryCtx, r.opts.Queries.ResolveID(), appID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up addresses: %w", err)
+	}
+	defer rows.Close()
+
+	addrs := make(nameresolution.AddressList, 0)
+	for rows.Next() {
+		var addr string
+		err = rows.Scan(&addr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read address: %w", err)
+		}
+		addrs = append(addrs, addr)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up addresses: %w", err)
+	}
+
+	return addrs, nil
+}
+
+// LookupByAddress returns the app IDs registered at an address, sorted alphabetically.
+// If the address doesn't include a port, it returns the app IDs registered on any port of that host.
+// Expired registrations that haven't been removed by the garbage collector yet are included too.
+func (r *Resolver) LookupByAddress(ctx context.Context, address string) ([]string, error) {
+	// If there's no port, match all addresses for the host
+	// The pattern matches nothing otherwise, as addresses are never empty
+	var pattern string
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		pattern = likeEscaper.Replace(net.JoinHostPort(address, "")) + "%"
+	}
+
+	queryCtx, queryCancel := context.WithTimeout(ctx, r.opts.Timeout)
+	defer queryCancel()
+	rows, err := r.opts.DB.Query(queryCtx, r.opts.Queries.LookupByAddress(), address, pattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up app IDs: %w", err)
+	}
+	defer rows.Close()
+
+	appIDs := make([]string, 0)
+	for rows.Next() {
+		var appID string
+		err = rows.Scan(&appID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read app ID: %w", err)
+		}
+		appIDs = append(appIDs, appID)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up app IDs: %w", err)
+	}
+	return appIDs, nil
+}
+
+// Escapes the special characters in LIKE patterns
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// Removes the registration for the host
+func (r *Resolver) deregisterHost(ctx context.Context) error {
+	if r.registrationID == "" {
+		// We never registered
+		return nil
+	}
+
+	queryCtx, queryCancel := context.WithTimeout(ctx, r.opts.Timeout)
+	defer queryCancel()
+
+	n, err := r.opts.DB.Exec(queryCtx, r.opts.Queries.DeregisterHost(),
+		r.registrationID, r.opts.Address,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to unregister host: %w", err)
+	}
+	if n == 0 {
+		return errors.New("failed to unregister host: no row deleted")
+	}
+
+	return nil
+}
+
+// Close stops the background operations and removes the registration of the host.
+// It does not close the database connection, which is owned by the caller.
+func (r *Resolver) Close() (err error) {
+	if !r.closed.CompareAndSwap(false, true) {
+		r.wg.Wait()
+		return nil
+	}
+
+	close(r.closeCh)
+	r.wg.Wait()
+
+	errs := make([]error, 0)
+
+	if r.gc != nil {
+		err = r.gc.Close()
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	err = r.deregisterHost(context.Background())
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
@@ -32,6 +32,28 @@ var (
 	algsParsed sync.Once
 )
 
+// Converts the list of algorithms from the Azure SDK, which returns a slice, into a map.
+// This function does not make network calls, and it's a no-op after the first invocation.
+func parseAlgorithms() {
+	algsParsed.Do(func() {
+		listEncryption := azkeys.PossibleEncryptionAlgorithmValues()
+		validEncryptionAlgs = make(map[string]struct{}, len(listEncryption))
+		encryptionAlgsList = make([]string, len(listEncryption))
+		for i, v := range listEncryption {
+			validEncryptionAlgs[string(v)] = struct{}{}
+			encryptionAlgsList[i] = string(v)
+		}
+
+		listSignature := azkeys.PossibleSignatureAlgorithmValues()
+		validSignatureAlgs = make(map[string]struct{}, len(listSignature))
+		signatureAlgsList = make([]string, len(listSignature))
+		for i, v := range listSignature {
+			validSignatureAlgs[string(v)] = struct{}{}
+			signatureAlgsList[i] = string(v)
+		}
+	})
+}
+
 // GetJWKEncryptionAlgorithm returns a JSONWebKeyEncryptionAlgorithm constant is the algorithm is a supported one.
 func GetJWKEncryptionAlgorithm(algorithm string) *azkeys.EncryptionAlgorithm {
 	// Special case for AES-CBC, since we treat A[NNN]CBC as having PKCS#7 padding, and A[NNN]CBC-NOPAD as not using padding
@@ -60,6 +82,30 @@ func GetJWKSignatureAlgorithm(algorithm string) *azkeys.SignatureAlgorithm {
 	}
 }
 
+// IsLocalEncryptionAlgorithm returns true if the encryption algorithm can be used locally, with the public part of a key, to encrypt or wrap data.
+// Data encrypted locally with these algorithms can be decrypted (or unwrapped) in the vault.
+func IsLocalEncryptionAlgorithm(algorithm azkeys.EncryptionAlgorithm) bool {
+	switch algorithm {
+	case azkeys.EncryptionAlgorithmRSA15, azkeys.EncryptionAlgorithmRSAOAEP, azkeys.EncryptionAlgorithmRSAOAEP256:
+		return true
+	default:
+		return false
+	}
+}
+
+// NonceSize returns the size in bytes of the nonce (or IV) used by the encryption algorithm, or 0 if the algorithm doesn't use one.
+func NonceSize(algorithm azkeys.EncryptionAlgorithm) int {
+	switch algorithm {
+	case azkeys.EncryptionAlgorithmA128GCM, azkeys.EncryptionAlgorithmA192GCM, azkeys.EncryptionAlgorithmA256GCM:
+		return 12
+	case azkeys.EncryptionAlgorithmA128CBC, azkeys.EncryptionAlgorithmA192CBC, azkeys.EncryptionAlgorithmA256CBC,
+		azkeys.EncryptionAlgorithmA128CBCPAD, azkeys.EncryptionAlgorithmA192CBCPAD, azkeys.EncryptionAlgorithmA256CBCPAD:
+		return 16
+	default:
+		return 0
+	}
+}
+
 type algorithms interface {
 	azkeys.EncryptionAlgorithm | azkeys.SignatureAlgorithm
 }
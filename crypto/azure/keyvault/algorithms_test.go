@@ -0,0 +1,68 @@
+/*
+Copyright 2024 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keyvault
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/security/keyvault/azkeys"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	internals "github.com/dapr/kit/crypto"
+)
+
+func TestIsLocalEncryptionAlgorithm(t *testing.T) {
+	for _, alg := range azkeys.PossibleEncryptionAlgorithmValues() {
+		switch alg {
+		case azkeys.EncryptionAlgorithmRSA15, azkeys.EncryptionAlgorithmRSAOAEP, azkeys.EncryptionAlgorithmRSAOAEP256:
+			assert.Truef(t, IsLocalEncryptionAlgorithm(alg), "algorithm %s should be supported locally", alg)
+		default:
+			assert.Falsef(t, IsLocalEncryptionAlgorithm(alg), "algorithm %s should not be supported locally", alg)
+		}
+	}
+}
+
+func TestEncryptPublicKeyLocal(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	privateKey, err := jwk.FromRaw(rsaKey)
+	require.NoError(t, err)
+	publicKey, err := privateKey.PublicKey()
+	require.NoError(t, err)
+
+	// Wrap an AES-256 key
+	aesKey := make([]byte, 32)
+	_, err = rand.Read(aesKey)
+	require.NoError(t, err)
+	plaintextKey, err := jwk.FromRaw(aesKey)
+	require.NoError(t, err)
+	plaintext, err := internals.SerializeKey(plaintextKey)
+	require.NoError(t, err)
+
+	for _, alg := range []string{internals.Algorithm_RSA_OAEP, internals.Algorithm_RSA_OAEP_256} {
+		t.Run(alg, func(t *testing.T) {
+			wrapped, err := encryptPublicKeyLocal(plaintext, alg, publicKey)
+			require.NoError(t, err)
+
+			// Key Vault unwraps without a label, so decrypting with no associated data must succeed
+			unwrapped, err := internals.DecryptPrivateKey(wrapped, alg, privateKey, nil)
+			require.NoError(t, err)
+			assert.Equal(t, aesKey, unwrapped)
+		})
+	}
+}
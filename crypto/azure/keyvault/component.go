@@ -15,16 +15,21 @@ package keyvault
 
 import (
 	"context"
+	"crypto/rand"
 	"errors"
 	"fmt"
+	"io"
 	"reflect"
 	"strings"
+	"sync"
+	"sync/atomic"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
 	"github.com/Azure/azure-sdk-for-go/sdk/security/keyvault/azkeys"
 	"github.com/lestrrat-go/jwx/v2/jwa"
 	"github.com/lestrrat-go/jwx/v2/jwk"
+	"k8s.io/utils/clock"
 
 	contribCrypto "github.com/dapr/components-contrib/crypto"
 	contribMetadata "github.com/dapr/components-contrib/metadata"
@@ -35,41 +40,36 @@ import (
 var errKeyNotFound = errors.New("key not found in the vault")
 
 type keyvaultCrypto struct {
-	keyCache    *contribCrypto.PubKeyCache
-	md          keyvaultMetadata
-	vaultClient *azkeys.Client
-	logger      logger.Logger
+	keyCache      *contribCrypto.PubKeyCache
+	notFoundCache *notFoundCache
+	md            keyvaultMetadata
+	vaultClient   *azkeys.Client
+	logger        logger.Logger
+	clock         clock.WithTicker
+
+	// Latest version of keys referenced without a version
+	latestVersions     map[string]string
+	latestVersionsLock sync.RWMutex
+
+	closed  atomic.Bool
+	closeCh chan struct{}
+	wg      sync.WaitGroup
 }
 
 // NewAzureKeyvaultCrypto returns a new Azure Key Vault crypto provider.
 func NewAzureKeyvaultCrypto(logger logger.Logger) contribCrypto.SubtleCrypto {
 	return &keyvaultCrypto{
-		logger: logger,
+		logger:         logger,
+		clock:          clock.RealClock{},
+		latestVersions: make(map[string]string),
+		closeCh:        make(chan struct{}),
 	}
 }
 
 // Init creates a Azure Key Vault client.
 func (k *keyvaultCrypto) Init(_ context.Context, metadata contribCrypto.Metadata) error {
-	// Convert from data from the Azure SDK, which returns a slice, into a map
 	// We perform the initialization here, lazily, when the first component of this kind is initialized
-	// (These functions do not make network calls)
-	algsParsed.Do(func() {
-		listEncryption := azkeys.PossibleEncryptionAlgorithmValues()
-		validEncryptionAlgs = make(map[string]struct{}, len(listEncryption))
-		encryptionAlgsList = make([]string, len(listEncryption))
-		for i, v := range listEncryption {
-			validEncryptionAlgs[string(v)] = struct{}{}
-			encryptionAlgsList[i] = string(v)
-		}
-
-		listSignature := azkeys.PossibleSignatureAlgorithmValues()
-		validSignatureAlgs = make(map[string]struct{}, len(listSignature))
-		signatureAlgsList = make([]string, len(listSignature))
-		for i, v := range listSignature {
-			validSignatureAlgs[string(v)] = struct{}{}
-			signatureAlgsList[i] = string(v)
-		}
-	})
+	parseAlgorithms()
 
 	// Init the metadata
 	err := k.md.InitWithMetadata(metadata)
@@ -79,50 +79,130 @@ func (k *keyvaultCrypto) Init(_ context.Context, metadata contribCrypto.Metadata
 
 	// Create a cache for keys
 	k.keyCache = contribCrypto.NewPubKeyCache(k.getKeyCacheFn)
+	k.notFoundCache = newNotFoundCache(k.md.NotFoundCacheTTL)
 
 	// Init the Azure SDK client
-	k.vaultClient, err = azkeys.NewClient(k.getVaultURI(), k.md.cred, &azkeys.ClientOptions{
+	k.vaultClient, err = azkeys.NewClient(k.getVaultURI(), k.md.cred, k.getClientOptions())
+	if err != nil {
+		return err
+	}
+
+	// Start refreshing the latest version of keys in background
+	if k.md.LatestKeyRefreshInterval > 0 {
+		k.wg.Add(1)
+		go k.refreshLatestVersions()
+	}
+
+	return nil
+}
+
+// Returns the options for the Azure SDK client.
+func (k *keyvaultCrypto) getClientOptions() *azkeys.ClientOptions {
+	return &azkeys.ClientOptions{
 		ClientOptions: azcore.ClientOptions{
 			Telemetry: policy.TelemetryOptions{
 				ApplicationID: "dapr-" + logger.DaprVersion,
 			},
+			// Transient errors, including throttling, are retried by vaultRequest, so we disable retries in the Azure SDK
+			// A negative value for MaxRetries means no retries
+			Retry: policy.RetryOptions{
+				MaxRetries: -1,
+			},
 		},
-	})
-	if err != nil {
-		return err
 	}
+}
 
+// Close implements the io.Closer interface to close the component
+func (k *keyvaultCrypto) Close() error {
+	if k.closed.CompareAndSwap(false, true) {
+		close(k.closeCh)
+	}
+
+	k.wg.Wait()
 	return nil
 }
 
 // Features returns the features available in this crypto provider.
 func (k *keyvaultCrypto) Features() []contribCrypto.Feature {
-	return []contribCrypto.Feature{} // No Feature supported.
+	// All operations can be performed, as the vault holds private and symmetric keys
+	return append(contribCrypto.OperationFeatures(true),
+		// Decrypt, UnwrapKey, and Sign always happen in the vault, as do all operations with symmetric keys
+		contribCrypto.FeatureVaultOperations,
+		// Encrypt, WrapKey, and Verify happen locally when the key has a version and the algorithm is supported
+		contribCrypto.FeatureLocalPublicKeyOperations,
+	)
+}
+
+// PubKeyCacheStats returns the number of cache hits and misses for the public keys cached locally.
+// Only keys with a version set are cached, so they are the only ones reported.
+// Implements the contribCrypto.SubtleCryptoCacheStats interface.
+func (k *keyvaultCrypto) PubKeyCacheStats() map[string]contribCrypto.PubKeyCacheStats {
+	if k.keyCache == nil {
+		return nil
+	}
+	return k.keyCache.Stats()
+}
+
+// ListKeys returns the names of the keys stored in the vault, without versions.
+// Implements the contribCrypto.SubtleCryptoKeyLister interface.
+func (k *keyvaultCrypto) ListKeys(parentCtx context.Context) ([]string, error) {
+	names := make([]string, 0)
+	pager := k.vaultClient.NewListKeyPropertiesPager(nil)
+	for pager.More() {
+		// Each page is a separate request, so we apply the timeout and retries to each
+		page, err := vaultRequest(parentCtx, k, pager.NextPage)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list keys from Key Vault: %w", err)
+		}
+
+		for _, item := range page.Value {
+			if item == nil || item.KID == nil {
+				continue
+			}
+			names = append(names, item.KID.Name())
+		}
+	}
+
+	return names, nil
 }
 
 // GetKey returns the public part of a key stored in the vault.
-// This method returns an error if the key is symmetric.
+// This method returns ErrKeyIsSymmetric if the key is symmetric.
 // The key argument can be in the format "name" or "name/version".
 func (k *keyvaultCrypto) GetKey(parentCtx context.Context, key string) (pubKey jwk.Key, err error) {
 	kid := newKeyID(key)
+	if k.notFoundCache.IsNotFound(kid) {
+		return nil, errKeyNotFound
+	}
+
+	kid, err = k.resolveKeyID(parentCtx, kid)
+	if err != nil {
+		return nil, err
+	}
 
 	// If the key is cacheable, get it from the cache
 	if kid.Cacheable() {
-		return k.keyCache.GetKey(parentCtx, key)
+		return k.keyCache.GetKey(parentCtx, kid.String())
 	}
 
 	return k.getKeyFromVault(parentCtx, kid)
 }
 
 func (k *keyvaultCrypto) getKeyFromVault(parentCtx context.Context, kid keyID) (pubKey jwk.Key, err error) {
-	ctx, cancel := context.WithTimeout(parentCtx, k.md.RequestTimeout)
-	res, err := k.vaultClient.GetKey(ctx, kid.Name, kid.Version, nil)
-	cancel()
+	res, err := vaultRequest(parentCtx, k, func(ctx context.Context) (azkeys.GetKeyResponse, error) {
+		return k.vaultClient.GetKey(ctx, kid.Name, kid.Version, nil)
+	})
 	if err != nil {
+		k.notFoundCache.Record(kid, err)
 		return nil, fmt.Errorf("failed to get key from Key Vault: %w", err)
 	}
 
-	return KeyBundleToKey(&res.KeyBundle)
+	pubKey, err = KeyBundleToKey(&res.KeyBundle)
+	if err != nil {
+		k.notFoundCache.Record(kid, err)
+		return nil, err
+	}
+	return pubKey, nil
 }
 
 // Handler for the getKeyCacheFn method
@@ -140,77 +220,140 @@ func (k *keyvaultCrypto) getKeyCacheFn(ctx context.Context, key string) func(res
 
 // Encrypt a small message and returns the ciphertext.
 // The key argument can be in the format "name" or "name/version".
+// If the algorithm uses a nonce, such as AES-GCM, and none is passed, the request is sent to the vault as-is; use EncryptWithNonce to have one generated and returned.
 func (k *keyvaultCrypto) Encrypt(parentCtx context.Context, plaintext []byte, algorithmStr string, key string, nonce []byte, associatedData []byte) (ciphertext []byte, tag []byte, err error) {
+	ciphertext, tag, _, err = k.encrypt(parentCtx, plaintext, algorithmStr, key, nonce, associatedData, false)
+	return ciphertext, tag, err
+}
+
+// EncryptWithNonce encrypts a small message and returns the ciphertext and the nonce that was used.
+// If the algorithm uses a nonce, such as AES-GCM, and none is passed, a random one is generated.
+// The key argument can be in the format "name" or "name/version".
+// Implements the contribCrypto.SubtleCryptoNonceGenerator interface.
+func (k *keyvaultCrypto) EncryptWithNonce(parentCtx context.Context, plaintext []byte, algorithmStr string, key string, nonce []byte, associatedData []byte) (ciphertext []byte, tag []byte, usedNonce []byte, err error) {
+	return k.encrypt(parentCtx, plaintext, algorithmStr, key, nonce, associatedData, true)
+}
+
+func (k *keyvaultCrypto) encrypt(parentCtx context.Context, plaintext []byte, algorithmStr string, key string, nonce []byte, associatedData []byte, generateNonce bool) (ciphertext []byte, tag []byte, usedNonce []byte, err error) {
 	kid := newKeyID(key)
+	if k.notFoundCache.IsNotFound(kid) {
+		return nil, nil, nil, errKeyNotFound
+	}
 
 	algorithm := GetJWKEncryptionAlgorithm(algorithmStr)
 	if algorithm == nil {
-		return nil, nil, fmt.Errorf("invalid algorithm: %s", algorithmStr)
+		return nil, nil, nil, fmt.Errorf("invalid algorithm: %s", algorithmStr)
+	}
+	err = checkAssociatedData(*algorithm, associatedData)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// Generate a nonce if needed, so it can be returned to the caller, who needs it to decrypt the data
+	if size := NonceSize(*algorithm); generateNonce && size > 0 && len(nonce) == 0 {
+		nonce = make([]byte, size)
+		_, err = io.ReadFull(rand.Reader, nonce)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to generate nonce: %w", err)
+		}
 	}
 
-	// Encrypting with symmetric or non-cacheable keys must happen in the vault
-	if !kid.Cacheable() || !IsAlgorithmAsymmetric(*algorithm) {
+	if IsLocalEncryptionAlgorithm(*algorithm) {
+		kid, err = k.resolveKeyID(parentCtx, kid)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	// Encrypting with non-cacheable keys, or with algorithms that can't be used locally, must happen in the vault
+	if !kid.Cacheable() || !IsLocalEncryptionAlgorithm(*algorithm) {
 		return k.encryptInVault(parentCtx, plaintext, algorithm, kid, nonce, associatedData)
 	}
 
 	// Using a cacheable, asymmetric key, we can encrypt the data directly here
-	pk, err := k.keyCache.GetKey(parentCtx, key)
+	pk, err := k.keyCache.GetKey(parentCtx, kid.String())
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to retrieve public key: %w", err)
+		return nil, nil, nil, fmt.Errorf("failed to retrieve public key: %w", err)
 	}
 
 	// If the key has expired, we cannot use that to encrypt data
 	if dpk, ok := pk.(*contribCrypto.Key); ok && !dpk.IsValid() {
-		return nil, nil, errors.New("the key is outside of its time validity bounds")
+		return nil, nil, nil, errors.New("the key is outside of its time validity bounds")
 	}
 
-	ciphertext, err = internals.EncryptPublicKey(plaintext, algorithmStr, pk, associatedData)
+	ciphertext, err = encryptPublicKeyLocal(plaintext, algorithmStr, pk)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to encrypt data: %w", err)
+		return nil, nil, nil, fmt.Errorf("failed to encrypt data: %w", err)
 	}
-	return ciphertext, nil, nil
+	return ciphertext, nil, nil, nil
 }
 
-func (k *keyvaultCrypto) encryptInVault(parentCtx context.Context, plaintext []byte, algorithm *azkeys.EncryptionAlgorithm, kid keyID, nonce []byte, associatedData []byte) (ciphertext []byte, tag []byte, err error) {
-	ctx, cancel := context.WithTimeout(parentCtx, k.md.RequestTimeout)
-	res, err := k.vaultClient.Encrypt(ctx, kid.Name, kid.Version, azkeys.KeyOperationParameters{
-		Algorithm:                   algorithm,
-		Value:                       plaintext,
-		IV:                          nonce,
-		AdditionalAuthenticatedData: associatedData,
-	}, nil)
-	cancel()
+func (k *keyvaultCrypto) encryptInVault(parentCtx context.Context, plaintext []byte, algorithm *azkeys.EncryptionAlgorithm, kid keyID, nonce []byte, associatedData []byte) (ciphertext []byte, tag []byte, usedNonce []byte, err error) {
+	res, err := vaultRequest(parentCtx, k, func(ctx context.Context) (azkeys.EncryptResponse, error) {
+		return k.vaultClient.Encrypt(ctx, kid.Name, kid.Version, azkeys.KeyOperationParameters{
+			Algorithm:                   algorithm,
+			Value:                       plaintext,
+			IV:                          nonce,
+			AdditionalAuthenticatedData: associatedData,
+		}, nil)
+	})
 	if err != nil {
-		return nil, nil, fmt.Errorf("error from Key Vault: %w", err)
+		k.notFoundCache.Record(kid, err)
+		return nil, nil, nil, fmt.Errorf("error from Key Vault: %w", err)
 	}
 
 	if res.Result == nil {
-		return nil, nil, errors.New("response from Key Vault does not contain a valid ciphertext")
+		return nil, nil, nil, errors.New("response from Key Vault does not contain a valid ciphertext")
 	}
 
-	return res.Result, res.AuthenticationTag, nil
+	// Return the IV used by the vault, if any
+	usedNonce = nonce
+	if len(res.IV) > 0 {
+		usedNonce = res.IV
+	}
+
+	return res.Result, res.AuthenticationTag, usedNonce, nil
+}
+
+// Returns an error if associated data is passed with a RSA algorithm.
+// Key Vault does not support labels with RSA-OAEP, so the ciphertext would not be bound to the associated data.
+func checkAssociatedData(algorithm azkeys.EncryptionAlgorithm, associatedData []byte) error {
+	if len(associatedData) > 0 && IsLocalEncryptionAlgorithm(algorithm) {
+		return fmt.Errorf("associated data is not supported with algorithm %s", algorithm)
+	}
+	return nil
+}
+
+// Encrypts data locally with the public part of a key.
+// Key Vault does not support labels with RSA-OAEP, so no label is used: this way, the result can always be decrypted in the vault.
+func encryptPublicKeyLocal(plaintext []byte, algorithm string, pk jwk.Key) ([]byte, error) {
+	return internals.EncryptPublicKey(plaintext, algorithm, pk, nil)
 }
 
 // Decrypt a small message and returns the plaintext.
 // The key argument can be in the format "name" or "name/version".
 func (k *keyvaultCrypto) Decrypt(parentCtx context.Context, ciphertext []byte, algorithmStr string, key string, nonce []byte, tag []byte, associatedData []byte) (plaintext []byte, err error) {
 	kid := newKeyID(key)
+	if k.notFoundCache.IsNotFound(kid) {
+		return nil, errKeyNotFound
+	}
 
 	algorithm := GetJWKEncryptionAlgorithm(algorithmStr)
 	if algorithm == nil {
 		return nil, fmt.Errorf("invalid algorithm: %s", algorithmStr)
 	}
 
-	ctx, cancel := context.WithTimeout(parentCtx, k.md.RequestTimeout)
-	res, err := k.vaultClient.Decrypt(ctx, kid.Name, kid.Version, azkeys.KeyOperationParameters{
-		Algorithm:                   algorithm,
-		Value:                       ciphertext,
-		IV:                          nonce,
-		AuthenticationTag:           tag,
-		AdditionalAuthenticatedData: associatedData,
-	}, nil)
-	cancel()
+	res, err := vaultRequest(parentCtx, k, func(ctx context.Context) (azkeys.DecryptResponse, error) {
+		return k.vaultClient.Decrypt(ctx, kid.Name, kid.Version, azkeys.KeyOperationParameters{
+			Algorithm:                   algorithm,
+			Value:                       ciphertext,
+			IV:                          nonce,
+			AuthenticationTag:           tag,
+			AdditionalAuthenticatedData: associatedData,
+		}, nil)
+	})
 	if err != nil {
+		k.notFoundCache.Record(kid, err)
 		return nil, fmt.Errorf("error from Key Vault: %w", err)
 	}
 
@@ -234,19 +377,33 @@ func (k *keyvaultCrypto) WrapKey(parentCtx context.Context, plaintextKey jwk.Key
 	}
 
 	kid := newKeyID(key)
+	if k.notFoundCache.IsNotFound(kid) {
+		return nil, nil, errKeyNotFound
+	}
 
 	algorithm := GetJWKEncryptionAlgorithm(algorithmStr)
 	if algorithm == nil {
 		return nil, nil, fmt.Errorf("invalid algorithm: %s", algorithmStr)
 	}
+	err = checkAssociatedData(*algorithm, associatedData)
+	if err != nil {
+		return nil, nil, err
+	}
 
-	// Encrypting with symmetric or non-cacheable keys must happen in the vault
-	if !kid.Cacheable() || !IsAlgorithmAsymmetric(*algorithm) {
+	if IsLocalEncryptionAlgorithm(*algorithm) {
+		kid, err = k.resolveKeyID(parentCtx, kid)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// Wrapping with non-cacheable keys, or with algorithms that can't be used locally, must happen in the vault
+	if !kid.Cacheable() || !IsLocalEncryptionAlgorithm(*algorithm) {
 		return k.wrapKeyInVault(parentCtx, plaintext, algorithm, kid, nonce, associatedData)
 	}
 
 	// Using a cacheable, asymmetric key, we can encrypt the data directly here
-	pk, err := k.keyCache.GetKey(parentCtx, key)
+	pk, err := k.keyCache.GetKey(parentCtx, kid.String())
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to retrieve public key: %w", err)
 	}
@@ -256,7 +413,7 @@ func (k *keyvaultCrypto) WrapKey(parentCtx context.Context, plaintextKey jwk.Key
 		return nil, nil, errors.New("the key is outside of its time validity bounds")
 	}
 
-	wrappedKey, err = internals.EncryptPublicKey(plaintext, algorithmStr, pk, associatedData)
+	wrappedKey, err = encryptPublicKeyLocal(plaintext, algorithmStr, pk)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to wrap key: %w", err)
 	}
@@ -264,15 +421,16 @@ func (k *keyvaultCrypto) WrapKey(parentCtx context.Context, plaintextKey jwk.Key
 }
 
 func (k *keyvaultCrypto) wrapKeyInVault(parentCtx context.Context, plaintextKey []byte, algorithm *azkeys.EncryptionAlgorithm, kid keyID, nonce []byte, associatedData []byte) (wrappedKey []byte, tag []byte, err error) {
-	ctx, cancel := context.WithTimeout(parentCtx, k.md.RequestTimeout)
-	res, err := k.vaultClient.WrapKey(ctx, kid.Name, kid.Version, azkeys.KeyOperationParameters{
-		Algorithm:                   algorithm,
-		Value:                       plaintextKey,
-		IV:                          nonce,
-		AdditionalAuthenticatedData: associatedData,
-	}, nil)
-	cancel()
+	res, err := vaultRequest(parentCtx, k, func(ctx context.Context) (azkeys.WrapKeyResponse, error) {
+		return k.vaultClient.WrapKey(ctx, kid.Name, kid.Version, azkeys.KeyOperationParameters{
+			Algorithm:                   algorithm,
+			Value:                       plaintextKey,
+			IV:                          nonce,
+			AdditionalAuthenticatedData: associatedData,
+		}, nil)
+	})
 	if err != nil {
+		k.notFoundCache.Record(kid, err)
 		return nil, nil, fmt.Errorf("error from Key Vault: %w", err)
 	}
 
@@ -287,22 +445,26 @@ func (k *keyvaultCrypto) wrapKeyInVault(parentCtx context.Context, plaintextKey
 // The key argument can be in the format "name" or "name/version".
 func (k *keyvaultCrypto) UnwrapKey(parentCtx context.Context, wrappedKey []byte, algorithmStr string, key string, nonce []byte, tag []byte, associatedData []byte) (plaintextKey jwk.Key, err error) {
 	kid := newKeyID(key)
+	if k.notFoundCache.IsNotFound(kid) {
+		return nil, errKeyNotFound
+	}
 
 	algorithm := GetJWKEncryptionAlgorithm(algorithmStr)
 	if algorithm == nil {
 		return nil, fmt.Errorf("invalid algorithm: %s", algorithmStr)
 	}
 
-	ctx, cancel := context.WithTimeout(parentCtx, k.md.RequestTimeout)
-	res, err := k.vaultClient.UnwrapKey(ctx, kid.Name, kid.Version, azkeys.KeyOperationParameters{
-		Algorithm:                   algorithm,
-		Value:                       wrappedKey,
-		IV:                          nonce,
-		AuthenticationTag:           tag,
-		AdditionalAuthenticatedData: associatedData,
-	}, nil)
-	cancel()
+	res, err := vaultRequest(parentCtx, k, func(ctx context.Context) (azkeys.UnwrapKeyResponse, error) {
+		return k.vaultClient.UnwrapKey(ctx, kid.Name, kid.Version, azkeys.KeyOperationParameters{
+			Algorithm:                   algorithm,
+			Value:                       wrappedKey,
+			IV:                          nonce,
+			AuthenticationTag:           tag,
+			AdditionalAuthenticatedData: associatedData,
+		}, nil)
+	})
 	if err != nil {
+		k.notFoundCache.Record(kid, err)
 		return nil, fmt.Errorf("error from Key Vault: %w", err)
 	}
 
@@ -323,19 +485,23 @@ func (k *keyvaultCrypto) UnwrapKey(parentCtx context.Context, wrappedKey []byte,
 // The key argument can be in the format "name" or "name/version".
 func (k *keyvaultCrypto) Sign(parentCtx context.Context, digest []byte, algorithmStr string, key string) (signature []byte, err error) {
 	kid := newKeyID(key)
+	if k.notFoundCache.IsNotFound(kid) {
+		return nil, errKeyNotFound
+	}
 
 	algorithm := GetJWKSignatureAlgorithm(algorithmStr)
 	if algorithm == nil {
 		return nil, fmt.Errorf("invalid algorithm: %s", algorithmStr)
 	}
 
-	ctx, cancel := context.WithTimeout(parentCtx, k.md.RequestTimeout)
-	res, err := k.vaultClient.Sign(ctx, kid.Name, kid.Version, azkeys.SignParameters{
-		Algorithm: algorithm,
-		Value:     digest,
-	}, nil)
-	cancel()
+	res, err := vaultRequest(parentCtx, k, func(ctx context.Context) (azkeys.SignResponse, error) {
+		return k.vaultClient.Sign(ctx, kid.Name, kid.Version, azkeys.SignParameters{
+			Algorithm: algorithm,
+			Value:     digest,
+		}, nil)
+	})
 	if err != nil {
+		k.notFoundCache.Record(kid, err)
 		return nil, fmt.Errorf("error from Key Vault: %w", err)
 	}
 
@@ -350,19 +516,27 @@ func (k *keyvaultCrypto) Sign(parentCtx context.Context, digest []byte, algorith
 // The key argument can be in the format "name" or "name/version".
 func (k *keyvaultCrypto) Verify(parentCtx context.Context, digest []byte, signature []byte, algorithmStr string, key string) (valid bool, err error) {
 	kid := newKeyID(key)
+	if k.notFoundCache.IsNotFound(kid) {
+		return false, errKeyNotFound
+	}
 
 	algorithm := GetJWKSignatureAlgorithm(algorithmStr)
 	if algorithm == nil {
 		return false, fmt.Errorf("invalid algorithm: %s", algorithmStr)
 	}
 
+	kid, err = k.resolveKeyID(parentCtx, kid)
+	if err != nil {
+		return false, err
+	}
+
 	// Verifying with non-cacheable keys must happen in the vault
 	if !kid.Cacheable() {
 		return k.verifyInVault(parentCtx, digest, signature, algorithm, kid)
 	}
 
 	// Using a cacheable, asymmetric key, we can verify the data directly here
-	pk, err := k.keyCache.GetKey(parentCtx, key)
+	pk, err := k.keyCache.GetKey(parentCtx, kid.String())
 	if err != nil {
 		return false, fmt.Errorf("failed to retrieve public key: %w", err)
 	}
@@ -375,14 +549,15 @@ func (k *keyvaultCrypto) Verify(parentCtx context.Context, digest []byte, signat
 }
 
 func (k *keyvaultCrypto) verifyInVault(parentCtx context.Context, digest []byte, signature []byte, algorithm *azkeys.SignatureAlgorithm, kid keyID) (valid bool, err error) {
-	ctx, cancel := context.WithTimeout(parentCtx, k.md.RequestTimeout)
-	res, err := k.vaultClient.Verify(ctx, kid.Name, kid.Version, azkeys.VerifyParameters{
-		Algorithm: algorithm,
-		Digest:    digest,
-		Signature: signature,
-	}, nil)
-	cancel()
+	res, err := vaultRequest(parentCtx, k, func(ctx context.Context) (azkeys.VerifyResponse, error) {
+		return k.vaultClient.Verify(ctx, kid.Name, kid.Version, azkeys.VerifyParameters{
+			Algorithm: algorithm,
+			Digest:    digest,
+			Signature: signature,
+		}, nil)
+	})
 	if err != nil {
+		k.notFoundCache.Record(kid, err)
 		return false, fmt.Errorf("error from Key Vault: %w", err)
 	}
 
@@ -394,19 +569,20 @@ func (k *keyvaultCrypto) verifyInVault(parentCtx context.Context, digest []byte,
 }
 
 // getVaultURI returns Azure Key Vault URI.
+// For Managed HSM, the DNS suffix is the one for Managed HSM in the selected Azure cloud.
 func (k *keyvaultCrypto) getVaultURI() string {
 	return fmt.Sprintf("https://%s.%s", k.md.VaultName, k.md.vaultDNSSuffix)
 }
 
-func (keyvaultCrypto) SupportedEncryptionAlgorithms() []string {
+func (*keyvaultCrypto) SupportedEncryptionAlgorithms() []string {
 	return encryptionAlgsList
 }
 
-func (keyvaultCrypto) SupportedSignatureAlgorithms() []string {
+func (*keyvaultCrypto) SupportedSignatureAlgorithms() []string {
 	return signatureAlgsList
 }
 
-func (keyvaultCrypto) GetComponentMetadata() (metadataInfo contribMetadata.MetadataMap) {
+func (*keyvaultCrypto) GetComponentMetadata() (metadataInfo contribMetadata.MetadataMap) {
 	metadataStruct := keyvaultMetadata{}
 	contribMetadata.GetMetadataInfoFromStructType(reflect.TypeOf(metadataStruct), &metadataInfo, contribMetadata.CryptoType)
 	return
@@ -430,6 +606,14 @@ func newKeyID(val string) keyID {
 	return obj
 }
 
+// String returns the key ID in the format "name" or "name/version".
+func (id keyID) String() string {
+	if id.Version == "" {
+		return id.Name
+	}
+	return id.Name + "/" + id.Version
+}
+
 // Cacheable returns true if the key can be cached locally.
 func (id keyID) Cacheable() bool {
 	switch strings.ToLower(id.Version) {
@@ -0,0 +1,583 @@
+/*
+Copyright 2024 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keyvault
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/security/keyvault/azkeys"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	clocktesting "k8s.io/utils/clock/testing"
+
+	contribCrypto "github.com/dapr/components-contrib/crypto"
+	"github.com/dapr/kit/logger"
+)
+
+const testVaultURI = "https://foo.vault.azure.net"
+
+// Transport that returns responses from a function, for testing.
+// If the function returns nil, the request fails with the error of its context.
+type stubTransport struct {
+	calls   atomic.Int32
+	handler func(req *http.Request) *http.Response
+}
+
+func (s *stubTransport) Do(req *http.Request) (*http.Response, error) {
+	s.calls.Add(1)
+	res := s.handler(req)
+	if res == nil {
+		return nil, req.Context().Err()
+	}
+	return res, nil
+}
+
+func stubResponse(req *http.Request, status int, body string) *http.Response {
+	return &http.Response{
+		Request:    req,
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+	}
+}
+
+// Token credential that returns a static token.
+type stubCredential struct{}
+
+func (stubCredential) GetToken(context.Context, policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	return azcore.AccessToken{Token: "token", ExpiresOn: time.Now().Add(time.Hour)}, nil
+}
+
+func newTestKeyvaultCrypto(t *testing.T, transport *stubTransport) *keyvaultCrypto {
+	t.Helper()
+
+	return newTestKeyvaultCryptoWithMetadata(t, transport, nil)
+}
+
+func newTestKeyvaultCryptoWithMetadata(t *testing.T, transport *stubTransport, setMetadata func(md *keyvaultMetadata)) *keyvaultCrypto {
+	t.Helper()
+
+	parseAlgorithms()
+	k := NewAzureKeyvaultCrypto(logger.NewLogger("test")).(*keyvaultCrypto)
+	k.md.reset()
+	if setMetadata != nil {
+		setMetadata(&k.md)
+	}
+
+	opts := k.getClientOptions()
+	opts.Transport = transport
+	client, err := azkeys.NewClient(testVaultURI, stubCredential{}, opts)
+	require.NoError(t, err)
+
+	k.vaultClient = client
+	k.keyCache = contribCrypto.NewPubKeyCache(k.getKeyCacheFn)
+	k.notFoundCache = newNotFoundCache(k.md.NotFoundCacheTTL)
+	return k
+}
+
+func TestListKeys(t *testing.T) {
+	t.Run("collects names from all pages", func(t *testing.T) {
+		transport := &stubTransport{
+			handler: func(req *http.Request) *http.Response {
+				if req.URL.Query().Get("page") == "2" {
+					return stubResponse(req, http.StatusOK, `{"value":[{"kid":"`+testVaultURI+`/keys/key3"}]}`)
+				}
+				return stubResponse(req, http.StatusOK, `{"value":[{"kid":"`+testVaultURI+`/keys/key1"},{"kid":"`+testVaultURI+`/keys/key2"}],"nextLink":"`+testVaultURI+`/keys?page=2"}`)
+			},
+		}
+		k := newTestKeyvaultCrypto(t, transport)
+
+		names, err := k.ListKeys(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, []string{"key1", "key2", "key3"}, names)
+		assert.EqualValues(t, 2, transport.calls.Load())
+	})
+
+	t.Run("stops when the context is canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		transport := &stubTransport{
+			handler: func(req *http.Request) *http.Response {
+				// Cancel the context after the first page is returned
+				cancel()
+				return stubResponse(req, http.StatusOK, `{"value":[{"kid":"`+testVaultURI+`/keys/key1"}],"nextLink":"`+testVaultURI+`/keys?page=2"}`)
+			},
+		}
+		k := newTestKeyvaultCrypto(t, transport)
+
+		_, err := k.ListKeys(ctx)
+		require.ErrorIs(t, err, context.Canceled)
+		assert.EqualValues(t, 1, transport.calls.Load())
+	})
+}
+
+// Returns the JSON of a key bundle response containing a RSA public key.
+func testKeyBundleJSON(t *testing.T, kid string) string {
+	t.Helper()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	n := base64.RawURLEncoding.EncodeToString(rsaKey.N.Bytes())
+	e := base64.RawURLEncoding.EncodeToString([]byte{1, 0, 1})
+	return `{"key":{"kid":"` + testVaultURI + `/keys/` + kid + `","kty":"RSA","n":"` + n + `","e":"` + e + `"},"attributes":{"enabled":true}}`
+}
+
+func TestGetKeySymmetric(t *testing.T) {
+	for _, kty := range []string{"oct", "oct-HSM"} {
+		t.Run(kty, func(t *testing.T) {
+			transport := &stubTransport{
+				handler: func(req *http.Request) *http.Response {
+					// Key Vault never returns the value of symmetric keys
+					return stubResponse(req, http.StatusOK, `{"key":{"kid":"`+testVaultURI+`/keys/mykey/1","kty":"`+kty+`"},"attributes":{"enabled":true}}`)
+				},
+			}
+			k := newTestKeyvaultCrypto(t, transport)
+
+			_, err := k.GetKey(context.Background(), "mykey/1")
+			require.ErrorIs(t, err, ErrKeyIsSymmetric)
+			_, err = k.GetKey(context.Background(), "mykey")
+			require.ErrorIs(t, err, ErrKeyIsSymmetric)
+		})
+	}
+}
+
+func TestNotFoundCache(t *testing.T) {
+	keyExists := atomic.Bool{}
+	bundle := testKeyBundleJSON(t, "mykey/1")
+	transport := &stubTransport{
+		handler: func(req *http.Request) *http.Response {
+			if !keyExists.Load() {
+				return stubResponse(req, http.StatusNotFound, `{"error":{"code":"KeyNotFound","message":"not found"}}`)
+			}
+			return stubResponse(req, http.StatusOK, bundle)
+		},
+	}
+	k := newTestKeyvaultCrypto(t, transport)
+	clock := clocktesting.NewFakeClock(time.Now())
+	k.notFoundCache.clock = clock
+
+	// First request goes to the vault
+	_, err := k.GetKey(context.Background(), "mykey")
+	require.Error(t, err)
+	assert.EqualValues(t, 1, transport.calls.Load())
+
+	// Repeated requests, including for other operations, do not hit the vault
+	_, err = k.GetKey(context.Background(), "mykey")
+	require.ErrorIs(t, err, errKeyNotFound)
+	_, _, err = k.Encrypt(context.Background(), []byte("message"), "RSA-OAEP", "mykey", nil, nil)
+	require.ErrorIs(t, err, errKeyNotFound)
+	assert.EqualValues(t, 1, transport.calls.Load())
+
+	// Other keys are not affected
+	_, err = k.GetKey(context.Background(), "otherkey")
+	require.Error(t, err)
+	assert.EqualValues(t, 2, transport.calls.Load())
+
+	// After the TTL, a key that was created is returned
+	keyExists.Store(true)
+	clock.Step(defaultNotFoundCacheTTL)
+	key, err := k.GetKey(context.Background(), "mykey")
+	require.NoError(t, err)
+	assert.Equal(t, "mykey/1", key.KeyID())
+	assert.EqualValues(t, 3, transport.calls.Load())
+
+	t.Run("disabled", func(t *testing.T) {
+		keyExists.Store(false)
+		transport.calls.Store(0)
+		k.notFoundCache = newNotFoundCache(0)
+
+		for i := 0; i < 2; i++ {
+			_, err = k.GetKey(context.Background(), "mykey")
+			require.Error(t, err)
+			assert.False(t, errors.Is(err, errKeyNotFound))
+		}
+		assert.EqualValues(t, 2, transport.calls.Load())
+	})
+
+	t.Run("bounded size", func(t *testing.T) {
+		c := newNotFoundCache(time.Minute)
+		c.clock = clock
+		c.maxItems = 3
+		errNotFound := &azcore.ResponseError{StatusCode: http.StatusNotFound}
+
+		c.Record(newKeyID("key1"), errNotFound)
+		clock.Step(time.Second)
+		c.Record(newKeyID("key2"), errNotFound)
+		c.Record(newKeyID("key3"), errNotFound)
+
+		// When the cache is full, the key that expires first is evicted
+		c.Record(newKeyID("key4"), errNotFound)
+		assert.Len(t, c.items, 3)
+		assert.False(t, c.IsNotFound(newKeyID("key1")))
+		assert.True(t, c.IsNotFound(newKeyID("key2")))
+		assert.True(t, c.IsNotFound(newKeyID("key4")))
+
+		// Expired keys are pruned even if they are not requested again
+		clock.Step(time.Minute)
+		c.Record(newKeyID("key5"), errNotFound)
+		c.Record(newKeyID("key6"), errNotFound)
+		assert.Len(t, c.items, 2)
+		assert.True(t, c.IsNotFound(newKeyID("key5")))
+		assert.True(t, c.IsNotFound(newKeyID("key6")))
+	})
+}
+
+func TestFeatures(t *testing.T) {
+	k := NewAzureKeyvaultCrypto(logger.NewLogger("test")).(*keyvaultCrypto)
+	assert.ElementsMatch(t, []contribCrypto.Feature{
+		contribCrypto.FeatureOperationEncrypt,
+		contribCrypto.FeatureOperationDecrypt,
+		contribCrypto.FeatureOperationWrapKey,
+		contribCrypto.FeatureOperationUnwrapKey,
+		contribCrypto.FeatureOperationSign,
+		contribCrypto.FeatureOperationVerify,
+		contribCrypto.FeatureVaultOperations,
+		contribCrypto.FeatureLocalPublicKeyOperations,
+	}, k.Features())
+}
+
+func TestLatestKeyVersions(t *testing.T) {
+	bundles := []string{
+		testKeyBundleJSON(t, "mykey/1"),
+		testKeyBundleJSON(t, "mykey/2"),
+	}
+	latest := atomic.Int32{}
+	transport := &stubTransport{
+		handler: func(req *http.Request) *http.Response {
+			switch req.URL.Path {
+			case "/keys/mykey/1":
+				return stubResponse(req, http.StatusOK, bundles[0])
+			case "/keys/mykey/2":
+				return stubResponse(req, http.StatusOK, bundles[1])
+			default:
+				// Latest version
+				return stubResponse(req, http.StatusOK, bundles[latest.Load()])
+			}
+		},
+	}
+	k := newTestKeyvaultCrypto(t, transport)
+	clock := clocktesting.NewFakeClock(time.Now())
+	k.clock = clock
+	k.md.LatestKeyRefreshInterval = time.Minute
+	k.wg.Add(1)
+	go k.refreshLatestVersions()
+	defer k.Close()
+
+	// Key is resolved to the latest version and cached
+	key, err := k.GetKey(context.Background(), "mykey")
+	require.NoError(t, err)
+	assert.Equal(t, "mykey/1", key.KeyID())
+	calls := transport.calls.Load()
+	key, err = k.GetKey(context.Background(), "mykey")
+	require.NoError(t, err)
+	assert.Equal(t, "mykey/1", key.KeyID())
+	assert.Equal(t, calls, transport.calls.Load())
+
+	// Rotate the key; the new version is picked up after the refresh interval
+	latest.Store(1)
+	assert.Eventually(t, clock.HasWaiters, time.Second, 10*time.Millisecond)
+	clock.Step(time.Minute)
+	assert.EventuallyWithT(t, func(c *assert.CollectT) {
+		key, err = k.GetKey(context.Background(), "mykey")
+		if assert.NoError(c, err) {
+			assert.Equal(c, "mykey/2", key.KeyID())
+		}
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// Keys with a version are not resolved
+	key, err = k.GetKey(context.Background(), "mykey/1")
+	require.NoError(t, err)
+	assert.Equal(t, "mykey/1", key.KeyID())
+}
+
+func TestRetryThrottling(t *testing.T) {
+	throttledResponse := func(req *http.Request, retryAfterMs string) *http.Response {
+		res := stubResponse(req, http.StatusTooManyRequests, `{"error":{"code":"Throttled","message":"too many requests"}}`)
+		res.Header.Set("Retry-After-Ms", retryAfterMs)
+		return res
+	}
+	signResponse := func(req *http.Request) *http.Response {
+		return stubResponse(req, http.StatusOK, `{"kid":"`+testVaultURI+`/keys/mykey/1","value":"c2lnbmF0dXJl"}`)
+	}
+
+	t.Run("succeeds after throttling", func(t *testing.T) {
+		transport := &stubTransport{}
+		transport.handler = func(req *http.Request) *http.Response {
+			if transport.calls.Load() <= 2 {
+				return throttledResponse(req, "10")
+			}
+			return signResponse(req)
+		}
+		k := newTestKeyvaultCrypto(t, transport)
+
+		signature, err := k.Sign(context.Background(), []byte("digest"), "RS256", "mykey/1")
+		require.NoError(t, err)
+		assert.Equal(t, []byte("signature"), signature)
+		assert.EqualValues(t, 3, transport.calls.Load())
+	})
+
+	t.Run("fails after max retries", func(t *testing.T) {
+		transport := &stubTransport{
+			handler: func(req *http.Request) *http.Response {
+				return throttledResponse(req, "10")
+			},
+		}
+		k := newTestKeyvaultCryptoWithMetadata(t, transport, func(md *keyvaultMetadata) {
+			md.MaxRetries = 1
+		})
+
+		_, err := k.Sign(context.Background(), []byte("digest"), "RS256", "mykey/1")
+		require.Error(t, err)
+		assert.EqualValues(t, 2, transport.calls.Load())
+	})
+
+	t.Run("retries disabled", func(t *testing.T) {
+		transport := &stubTransport{
+			handler: func(req *http.Request) *http.Response {
+				return throttledResponse(req, "10")
+			},
+		}
+		k := newTestKeyvaultCryptoWithMetadata(t, transport, func(md *keyvaultMetadata) {
+			md.MaxRetries = 0
+		})
+
+		_, err := k.Sign(context.Background(), []byte("digest"), "RS256", "mykey/1")
+		require.Error(t, err)
+		assert.EqualValues(t, 1, transport.calls.Load())
+	})
+
+	t.Run("returns the throttling error if the delay is longer than the request timeout", func(t *testing.T) {
+		transport := &stubTransport{
+			handler: func(req *http.Request) *http.Response {
+				return throttledResponse(req, "45000")
+			},
+		}
+		k := newTestKeyvaultCryptoWithMetadata(t, transport, func(md *keyvaultMetadata) {
+			md.RequestTimeout = 30 * time.Second
+			md.MaxRetryDelay = time.Minute
+		})
+
+		start := time.Now()
+		_, err := k.Sign(context.Background(), []byte("digest"), "RS256", "mykey/1")
+		var respErr *azcore.ResponseError
+		require.ErrorAs(t, err, &respErr)
+		assert.Equal(t, http.StatusTooManyRequests, respErr.StatusCode)
+		assert.NotErrorIs(t, err, context.DeadlineExceeded)
+		assert.EqualValues(t, 1, transport.calls.Load())
+		assert.Less(t, time.Since(start), 5*time.Second)
+	})
+
+	t.Run("context cancellation stops waiting", func(t *testing.T) {
+		transport := &stubTransport{
+			handler: func(req *http.Request) *http.Response {
+				return throttledResponse(req, "10000")
+			},
+		}
+		k := newTestKeyvaultCrypto(t, transport)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		time.AfterFunc(100*time.Millisecond, cancel)
+		start := time.Now()
+		_, err := k.Sign(ctx, []byte("digest"), "RS256", "mykey/1")
+		require.ErrorIs(t, err, context.Canceled)
+		assert.Less(t, time.Since(start), 5*time.Second)
+	})
+}
+
+func TestRequestTimeoutApplied(t *testing.T) {
+	transport := &stubTransport{
+		handler: func(req *http.Request) *http.Response {
+			// Simulate a request that doesn't complete until it's canceled
+			<-req.Context().Done()
+			return nil
+		},
+	}
+	k := newTestKeyvaultCryptoWithMetadata(t, transport, func(md *keyvaultMetadata) {
+		md.RequestTimeout = 100 * time.Millisecond
+	})
+
+	start := time.Now()
+	_, err := k.Sign(context.Background(), []byte("digest"), "RS256", "mykey/1")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestEncryptNonce(t *testing.T) {
+	// Records the IV sent to the vault and returns it in the response if returnIV is true
+	var (
+		sentIV   []byte
+		returnIV bool
+	)
+	transport := &stubTransport{
+		handler: func(req *http.Request) *http.Response {
+			var body struct {
+				IV string `json:"iv"`
+			}
+			// The body is read with GetBody because the SDK sets it so requests can be retried
+			r, err := req.GetBody()
+			if err == nil {
+				err = json.NewDecoder(r).Decode(&body)
+			}
+			if err != nil {
+				return stubResponse(req, http.StatusBadRequest, `{"error":{"code":"BadParameter","message":"invalid body"}}`)
+			}
+			sentIV, _ = base64.RawURLEncoding.DecodeString(body.IV)
+
+			res := `{"kid":"` + testVaultURI + `/keys/mykey/1","value":"Y2lwaGVydGV4dA","tag":"dGFn"`
+			if returnIV {
+				res += `,"iv":"` + base64.RawURLEncoding.EncodeToString([]byte("vault-iv1234")) + `"`
+			}
+			return stubResponse(req, http.StatusOK, res+`}`)
+		},
+	}
+	k := newTestKeyvaultCrypto(t, transport)
+
+	t.Run("nonce is generated when omitted", func(t *testing.T) {
+		ciphertext, tag, nonce, err := k.EncryptWithNonce(context.Background(), []byte("message"), "A256GCM", "mykey/1", nil, nil)
+		require.NoError(t, err)
+		assert.Equal(t, []byte("ciphertext"), ciphertext)
+		assert.Equal(t, []byte("tag"), tag)
+		assert.Len(t, nonce, 12)
+		assert.Equal(t, sentIV, nonce)
+
+		// Each nonce is random
+		_, _, nonce2, err := k.EncryptWithNonce(context.Background(), []byte("message"), "A256GCM", "mykey/1", nil, nil)
+		require.NoError(t, err)
+		assert.Len(t, nonce2, 12)
+		assert.NotEqual(t, nonce, nonce2)
+	})
+
+	t.Run("nonce is passed through", func(t *testing.T) {
+		_, _, nonce, err := k.EncryptWithNonce(context.Background(), []byte("message"), "A128GCM", "mykey/1", []byte("mynonce12345"), nil)
+		require.NoError(t, err)
+		assert.Equal(t, []byte("mynonce12345"), nonce)
+		assert.Equal(t, []byte("mynonce12345"), sentIV)
+	})
+
+	t.Run("nonce returned by the vault", func(t *testing.T) {
+		returnIV = true
+		defer func() { returnIV = false }()
+
+		_, _, nonce, err := k.EncryptWithNonce(context.Background(), []byte("message"), "A256GCM", "mykey/1", nil, nil)
+		require.NoError(t, err)
+		assert.Equal(t, []byte("vault-iv1234"), nonce)
+	})
+
+	t.Run("Encrypt sends a missing nonce to the vault as-is", func(t *testing.T) {
+		sentIV = []byte("previous")
+		transport.calls.Store(0)
+		ciphertext, _, err := k.Encrypt(context.Background(), []byte("message"), "A256GCM", "mykey/1", nil, nil)
+		require.NoError(t, err)
+		assert.Equal(t, []byte("ciphertext"), ciphertext)
+		assert.EqualValues(t, 1, transport.calls.Load())
+		assert.Empty(t, sentIV)
+	})
+}
+
+func TestWrapKeyLocal(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	n := base64.RawURLEncoding.EncodeToString(rsaKey.N.Bytes())
+	e := base64.RawURLEncoding.EncodeToString([]byte{1, 0, 1})
+
+	// The vault returns the public key, and unwraps keys with the private key like Key Vault does, using no OAEP label
+	var vaultWraps atomic.Int32
+	transport := &stubTransport{
+		handler: func(req *http.Request) *http.Response {
+			switch {
+			case req.Method == http.MethodGet:
+				return stubResponse(req, http.StatusOK, `{"key":{"kid":"`+testVaultURI+`/keys/mykey/1","kty":"RSA","n":"`+n+`","e":"`+e+`"},"attributes":{"enabled":true}}`)
+			case strings.HasSuffix(req.URL.Path, "/wrapkey"):
+				vaultWraps.Add(1)
+				return stubResponse(req, http.StatusBadRequest, `{"error":{"code":"BadParameter","message":"unexpected call"}}`)
+			case strings.HasSuffix(req.URL.Path, "/unwrapkey"):
+				var body struct {
+					Alg   string `json:"alg"`
+					Value string `json:"value"`
+				}
+				r, err := req.GetBody()
+				if err == nil {
+					err = json.NewDecoder(r).Decode(&body)
+				}
+				if err != nil {
+					return stubResponse(req, http.StatusBadRequest, `{"error":{"code":"BadParameter","message":"invalid body"}}`)
+				}
+				wrapped, _ := base64.RawURLEncoding.DecodeString(body.Value)
+				// RSA-OAEP uses SHA-1
+				//nolint:gosec
+				hash := sha1.New()
+				if body.Alg == "RSA-OAEP-256" {
+					hash = sha256.New()
+				}
+				unwrapped, err := rsa.DecryptOAEP(hash, nil, rsaKey, wrapped, nil)
+				if err != nil {
+					return stubResponse(req, http.StatusBadRequest, `{"error":{"code":"BadParameter","message":"decryption failed"}}`)
+				}
+				return stubResponse(req, http.StatusOK, `{"kid":"`+testVaultURI+`/keys/mykey/1","value":"`+base64.RawURLEncoding.EncodeToString(unwrapped)+`"}`)
+			}
+			return stubResponse(req, http.StatusNotFound, `{"error":{"code":"NotFound","message":"not found"}}`)
+		},
+	}
+	k := newTestKeyvaultCrypto(t, transport)
+
+	aesKey := make([]byte, 32)
+	_, err = rand.Read(aesKey)
+	require.NoError(t, err)
+	plaintextKey, err := jwk.FromRaw(aesKey)
+	require.NoError(t, err)
+
+	for _, alg := range []string{"RSA-OAEP", "RSA-OAEP-256"} {
+		t.Run(alg, func(t *testing.T) {
+			// The key is wrapped locally, and it can be unwrapped by the vault
+			wrapped, tag, err := k.WrapKey(context.Background(), plaintextKey, alg, "mykey/1", nil, nil)
+			require.NoError(t, err)
+			assert.Nil(t, tag)
+			assert.EqualValues(t, 0, vaultWraps.Load())
+
+			unwrapped, err := k.UnwrapKey(context.Background(), wrapped, alg, "mykey/1", nil, nil, nil)
+			require.NoError(t, err)
+			var raw []byte
+			require.NoError(t, unwrapped.Raw(&raw))
+			assert.Equal(t, aesKey, raw)
+		})
+	}
+
+	t.Run("associated data is rejected", func(t *testing.T) {
+		transport.calls.Store(0)
+		_, _, err := k.WrapKey(context.Background(), plaintextKey, "RSA-OAEP-256", "mykey/1", nil, []byte("aad"))
+		require.ErrorContains(t, err, "associated data is not supported with algorithm RSA-OAEP-256")
+		_, _, err = k.Encrypt(context.Background(), []byte("message"), "RSA-OAEP-256", "mykey/1", nil, []byte("aad"))
+		require.ErrorContains(t, err, "associated data is not supported with algorithm RSA-OAEP-256")
+		assert.EqualValues(t, 0, transport.calls.Load())
+	})
+}
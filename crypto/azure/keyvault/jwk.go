@@ -28,6 +28,9 @@ import (
 	contribCrypto "github.com/dapr/components-contrib/crypto"
 )
 
+// ErrKeyIsSymmetric is returned when trying to get the public part of a symmetric key.
+var ErrKeyIsSymmetric = errors.New("key is symmetric and its value cannot be retrieved from the vault")
+
 // KeyBundleToKey converts an azkeys.KeyBundle object to a contribCrypto.Key object, containing only the public part of the asymmetric key.
 func KeyBundleToKey(bundle *azkeys.KeyBundle) (*contribCrypto.Key, error) {
 	if bundle == nil ||
@@ -44,7 +47,9 @@ func KeyBundleToKey(bundle *azkeys.KeyBundle) (*contribCrypto.Key, error) {
 
 	// Extract the public key and create a jwk.Key from that
 	pk, err := JSONWebKey{*bundle.Key}.Public()
-	if err != nil {
+	if errors.Is(err, ErrKeyIsSymmetric) {
+		return nil, err
+	} else if err != nil {
 		return nil, fmt.Errorf("failed to extract public key as crypto.PublicKey: %w", err)
 	}
 	jwkObj, err := jwk.FromRaw(pk)
@@ -62,7 +67,7 @@ type JSONWebKey struct {
 }
 
 // Public returns the public key included the object, as a crypto.PublicKey object.
-// This method returns an error if it's invoked on a JSONWebKey object representing a symmetric key.
+// This method returns ErrKeyIsSymmetric if it's invoked on a JSONWebKey object representing a symmetric key.
 func (key JSONWebKey) Public() (crypto.PublicKey, error) {
 	if key.Kty == nil {
 		return nil, errors.New("property Kty is nil")
@@ -73,6 +78,8 @@ func (key JSONWebKey) Public() (crypto.PublicKey, error) {
 		return key.publicRSA()
 	case IsECKey(*key.Kty):
 		return key.publicEC()
+	case IsSymmetricKey(*key.Kty):
+		return nil, ErrKeyIsSymmetric
 	}
 
 	return nil, errors.New("unsupported key type")
@@ -140,3 +147,8 @@ func IsRSAKey(kt azkeys.KeyType) bool {
 func IsECKey(kt azkeys.KeyType) bool {
 	return kt == azkeys.KeyTypeEC || kt == azkeys.KeyTypeECHSM
 }
+
+// IsSymmetricKey returns true if the key is a symmetric key (oct or oct-HSM).
+func IsSymmetricKey(kt azkeys.KeyType) bool {
+	return kt == azkeys.KeyTypeOct || kt == azkeys.KeyTypeOctHSM
+}
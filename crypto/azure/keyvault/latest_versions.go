@@ -0,0 +1,118 @@
+/*
+Copyright 2024 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keyvault
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Resolves a key ID without a version (or with version "latest") to the latest version of the key, so it can be cached.
+// This is a no-op if resolving the latest version of keys is disabled, or if the key ID already has a version.
+func (k *keyvaultCrypto) resolveKeyID(ctx context.Context, kid keyID) (keyID, error) {
+	if k.md.LatestKeyRefreshInterval <= 0 || kid.Cacheable() {
+		return kid, nil
+	}
+
+	k.latestVersionsLock.RLock()
+	version, ok := k.latestVersions[kid.Name]
+	k.latestVersionsLock.RUnlock()
+	if ok {
+		return keyID{Name: kid.Name, Version: version}, nil
+	}
+
+	version, err := k.getLatestVersion(ctx, kid.Name)
+	if err != nil {
+		return kid, err
+	}
+
+	k.latestVersionsLock.Lock()
+	k.latestVersions[kid.Name] = version
+	k.latestVersionsLock.Unlock()
+
+	return keyID{Name: kid.Name, Version: version}, nil
+}
+
+// Retrieves the latest version of a key from the vault.
+func (k *keyvaultCrypto) getLatestVersion(ctx context.Context, name string) (string, error) {
+	pk, err := k.getKeyFromVault(ctx, keyID{Name: name})
+	if err != nil {
+		return "", err
+	}
+
+	_, version, _ := strings.Cut(pk.KeyID(), "/")
+	if version == "" {
+		return "", fmt.Errorf("key '%s' returned by the vault does not have a version", name)
+	}
+	return version, nil
+}
+
+// In background, periodically refreshes the latest version of keys that were resolved.
+// Should be invoked in a background goroutine.
+func (k *keyvaultCrypto) refreshLatestVersions() {
+	defer k.wg.Done()
+
+	t := k.clock.NewTicker(k.md.LatestKeyRefreshInterval)
+	defer t.Stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	for {
+		select {
+		case <-k.closeCh:
+			return
+		case <-t.C():
+			k.refreshLatestVersionsOnce(ctx)
+		}
+	}
+}
+
+func (k *keyvaultCrypto) refreshLatestVersionsOnce(ctx context.Context) {
+	k.latestVersionsLock.RLock()
+	names := make([]string, 0, len(k.latestVersions))
+	for name := range k.latestVersions {
+		names = append(names, name)
+	}
+	k.latestVersionsLock.RUnlock()
+
+	for _, name := range names {
+		version, err := k.getLatestVersion(ctx, name)
+		if err != nil {
+			k.logger.Warnf("Failed to refresh the latest version of key '%s': %v", name, err)
+			continue
+		}
+
+		k.latestVersionsLock.RLock()
+		changed := k.latestVersions[name] != version
+		k.latestVersionsLock.RUnlock()
+		if !changed {
+			continue
+		}
+
+		k.logger.Debugf("Key '%s' was rotated to version '%s'", name, version)
+
+		// Pre-load the new version in the cache before using it
+		_, err = k.keyCache.GetKey(ctx, name+"/"+version)
+		if err != nil {
+			k.logger.Warnf("Failed to retrieve the latest version of key '%s': %v", name, err)
+			continue
+		}
+
+		k.latestVersionsLock.Lock()
+		k.latestVersions[name] = version
+		k.latestVersionsLock.Unlock()
+	}
+}
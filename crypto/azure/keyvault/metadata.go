@@ -15,6 +15,7 @@ package keyvault
 
 import (
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
@@ -24,15 +25,47 @@ import (
 	"github.com/dapr/kit/metadata"
 )
 
-const defaultRequestTimeout = 30 * time.Second
+const (
+	defaultRequestTimeout   = 30 * time.Second
+	defaultNotFoundCacheTTL = 30 * time.Second
+	defaultMaxRetries       = 3
+	// The default maximum retry delay is half of requestTimeout, up to this value
+	defaultMaxRetryDelay = 60 * time.Second
+)
 
 type keyvaultMetadata struct {
 	// Name of the Azure Key Vault resource (required).
 	VaultName string `json:"vaultName" mapstructure:"vaultName"`
 
-	// Timeout for network requests, as a Go duration string (e.g. "30s")
+	// Timeout for network requests, as a Go duration string (e.g. "30s") or a number of seconds.
+	// This is the maximum time for each operation, including retries.
 	// Defaults to "30s".
-	RequestTimeout time.Duration `json:"requestTimeout" mapstructure:"requestTimeout"`
+	RequestTimeout time.Duration `json:"requestTimeout" mapstructure:"requestTimeout" mapstructurealiases:"requestTimeoutSeconds"`
+
+	// If true, the resource is an Azure Key Vault Managed HSM rather than a standard vault.
+	// Defaults to false.
+	ManagedHSM bool `json:"managedHSM" mapstructure:"managedHSM"`
+
+	// Time for which keys that were not found in the vault are remembered, as a Go duration string (e.g. "30s").
+	// During this time, requests for those keys fail without calling the vault. Set to "0" to disable.
+	// Defaults to "30s".
+	NotFoundCacheTTL time.Duration `json:"notFoundCacheTTL" mapstructure:"notFoundCacheTTL"`
+
+	// If set, keys referenced without a version are resolved to their latest version, which is cached and refreshed with this interval, as a Go duration string (e.g. "5m").
+	// This allows performing operations with public keys locally for keys referenced by name only. After a key is rotated, the new version is used after the next refresh.
+	// Defaults to "0", which disables this: keys without a version are always used in the vault.
+	LatestKeyRefreshInterval time.Duration `json:"latestKeyRefreshInterval" mapstructure:"latestKeyRefreshInterval"`
+
+	// Maximum number of times a request is retried after a transient failure, including throttling (HTTP 429).
+	// Set to 0 to disable retries.
+	// Defaults to 3.
+	MaxRetries int `json:"maxRetries" mapstructure:"maxRetries"`
+
+	// Maximum delay before retrying a request, as a Go duration string (e.g. "60s").
+	// Requests are retried after the delay indicated by the vault in the Retry-After header; if that is longer than this value, the request is not retried.
+	// Must not be longer than requestTimeout, which also includes the time spent waiting to retry.
+	// Defaults to half of requestTimeout, up to "60s".
+	MaxRetryDelay time.Duration `json:"maxRetryDelay" mapstructure:"maxRetryDelay"`
 
 	// Internal properties
 	vaultDNSSuffix string
@@ -54,19 +87,41 @@ func (m *keyvaultMetadata) InitWithMetadata(meta contribCrypto.Metadata) error {
 	}
 
 	// Set default requestTimeout if empty
-	if m.RequestTimeout < time.Second {
+	if m.RequestTimeout < 0 {
+		return errors.New("metadata property 'requestTimeout' must be positive")
+	}
+	if m.RequestTimeout == 0 {
 		m.RequestTimeout = defaultRequestTimeout
 	}
 
+	if m.MaxRetries < 0 {
+		return errors.New("metadata property 'maxRetries' must not be negative")
+	}
+	if m.MaxRetryDelay <= 0 {
+		m.MaxRetryDelay = min(m.RequestTimeout/2, defaultMaxRetryDelay)
+	}
+	if m.MaxRetryDelay > m.RequestTimeout {
+		return errors.New("metadata property 'maxRetryDelay' must not be longer than 'requestTimeout'")
+	}
+
 	// Get the DNS suffix
 	settings, err := azauth.NewEnvironmentSettings(meta.Properties)
 	if err != nil {
 		return err
 	}
-	m.vaultDNSSuffix = settings.EndpointSuffix(azauth.ServiceAzureKeyVault)
+	if m.ManagedHSM {
+		m.vaultDNSSuffix = settings.EndpointSuffix(azauth.ServiceAzureManagedHSM)
+	} else {
+		m.vaultDNSSuffix = settings.EndpointSuffix(azauth.ServiceAzureKeyVault)
+	}
 
 	// Get the credentials object
-	m.cred, err = settings.GetTokenCredential()
+	// If a client certificate file is configured, we use that and fail if it can't be loaded, rather than falling back to other authentication methods
+	if certFile, _ := settings.GetEnvironment("CertificateFile"); certFile != "" {
+		m.cred, err = getClientCertificateCredential(settings)
+	} else {
+		m.cred, err = settings.GetTokenCredential()
+	}
 	if err != nil {
 		return err
 	}
@@ -74,10 +129,29 @@ func (m *keyvaultMetadata) InitWithMetadata(meta contribCrypto.Metadata) error {
 	return nil
 }
 
+// Returns a credential for the client certificate configured with the azureCertificateFile and azureCertificatePassword metadata properties.
+func getClientCertificateCredential(settings azauth.EnvironmentSettings) (azcore.TokenCredential, error) {
+	certConfig, err := settings.GetClientCert()
+	if err != nil {
+		return nil, fmt.Errorf("invalid client certificate configuration: %w", err)
+	}
+	cred, err := certConfig.GetTokenCredential()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load client certificate from '%s' (check that the file exists and that the password is correct): %w", certConfig.CertificatePath, err)
+	}
+	return cred, nil
+}
+
 // Reset the object
 func (m *keyvaultMetadata) reset() {
 	m.VaultName = ""
 	m.RequestTimeout = defaultRequestTimeout
+	m.ManagedHSM = false
+	m.NotFoundCacheTTL = defaultNotFoundCacheTTL
+	m.LatestKeyRefreshInterval = 0
+	m.MaxRetries = defaultMaxRetries
+	// The default value depends on RequestTimeout, so it's set in InitWithMetadata
+	m.MaxRetryDelay = 0
 
 	m.vaultDNSSuffix = ""
 	m.cred = nil
@@ -0,0 +1,230 @@
+/*
+Copyright 2024 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keyvault
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	contribCrypto "github.com/dapr/components-contrib/crypto"
+	"github.com/dapr/components-contrib/metadata"
+)
+
+func TestVaultURI(t *testing.T) {
+	baseProps := map[string]string{
+		"vaultName":         "foo",
+		"azureTenantId":     "00000000-0000-0000-0000-000000000000",
+		"azureClientId":     "00000000-0000-0000-0000-000000000000",
+		"azureClientSecret": "passw0rd",
+	}
+
+	tests := []struct {
+		name  string
+		props map[string]string
+		want  string
+	}{
+		{
+			name:  "standard vault",
+			props: map[string]string{},
+			want:  "https://foo.vault.azure.net",
+		},
+		{
+			name:  "standard vault in Azure China",
+			props: map[string]string{"azureEnvironment": "AZURECHINACLOUD"},
+			want:  "https://foo.vault.azure.cn",
+		},
+		{
+			name:  "managed HSM",
+			props: map[string]string{"managedHSM": "true"},
+			want:  "https://foo.managedhsm.azure.net",
+		},
+		{
+			name:  "managed HSM in Azure China",
+			props: map[string]string{"managedHSM": "true", "azureEnvironment": "AZURECHINACLOUD"},
+			want:  "https://foo.managedhsm.azure.cn",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			props := make(map[string]string, len(baseProps)+len(tt.props))
+			for k, v := range baseProps {
+				props[k] = v
+			}
+			for k, v := range tt.props {
+				props[k] = v
+			}
+
+			k := &keyvaultCrypto{}
+			err := k.md.InitWithMetadata(contribCrypto.Metadata{Base: metadata.Base{Properties: props}})
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, k.getVaultURI())
+		})
+	}
+}
+
+// Writes a self-signed certificate and its private key, PEM-encoded, to a file and returns its path.
+func writeTestCertificate(t *testing.T) string {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "test"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	data = append(data, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})...)
+
+	path := filepath.Join(t.TempDir(), "cert.pem")
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+	return path
+}
+
+func TestRequestTimeout(t *testing.T) {
+	props := func(key string, val string) map[string]string {
+		p := map[string]string{
+			"vaultName":         "foo",
+			"azureTenantId":     "00000000-0000-0000-0000-000000000000",
+			"azureClientId":     "00000000-0000-0000-0000-000000000000",
+			"azureClientSecret": "passw0rd",
+		}
+		if key != "" {
+			p[key] = val
+		}
+		return p
+	}
+
+	tests := []struct {
+		name    string
+		props   map[string]string
+		want    time.Duration
+		wantErr bool
+	}{
+		{name: "default", props: props("", ""), want: defaultRequestTimeout},
+		{name: "zero uses default", props: props("requestTimeout", "0"), want: defaultRequestTimeout},
+		{name: "duration", props: props("requestTimeout", "45s"), want: 45 * time.Second},
+		{name: "less than one second", props: props("requestTimeout", "500ms"), want: 500 * time.Millisecond},
+		{name: "seconds", props: props("requestTimeoutSeconds", "10"), want: 10 * time.Second},
+		{name: "negative", props: props("requestTimeout", "-1s"), wantErr: true},
+		{name: "negative seconds", props: props("requestTimeoutSeconds", "-10"), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			md := keyvaultMetadata{}
+			err := md.InitWithMetadata(contribCrypto.Metadata{Base: metadata.Base{Properties: tt.props}})
+			if tt.wantErr {
+				require.ErrorContains(t, err, "metadata property 'requestTimeout' must be positive")
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, md.RequestTimeout)
+		})
+	}
+}
+
+func TestMaxRetryDelay(t *testing.T) {
+	props := func(props ...string) map[string]string {
+		p := map[string]string{
+			"vaultName":         "foo",
+			"azureTenantId":     "00000000-0000-0000-0000-000000000000",
+			"azureClientId":     "00000000-0000-0000-0000-000000000000",
+			"azureClientSecret": "passw0rd",
+		}
+		for i := 0; i < len(props); i += 2 {
+			p[props[i]] = props[i+1]
+		}
+		return p
+	}
+
+	tests := []struct {
+		name    string
+		props   map[string]string
+		want    time.Duration
+		wantErr bool
+	}{
+		{name: "default", props: props(), want: defaultRequestTimeout / 2},
+		{name: "default with long request timeout", props: props("requestTimeout", "5m"), want: defaultMaxRetryDelay},
+		{name: "set", props: props("maxRetryDelay", "10s"), want: 10 * time.Second},
+		{name: "equal to request timeout", props: props("maxRetryDelay", "20s", "requestTimeout", "20s"), want: 20 * time.Second},
+		{name: "longer than request timeout", props: props("maxRetryDelay", "60s"), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			md := keyvaultMetadata{}
+			err := md.InitWithMetadata(contribCrypto.Metadata{Base: metadata.Base{Properties: tt.props}})
+			if tt.wantErr {
+				require.ErrorContains(t, err, "metadata property 'maxRetryDelay' must not be longer than 'requestTimeout'")
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, md.MaxRetryDelay)
+		})
+	}
+}
+
+func TestClientCertificateFile(t *testing.T) {
+	props := func(certFile string) map[string]string {
+		return map[string]string{
+			"vaultName":                "foo",
+			"azureTenantId":            "00000000-0000-0000-0000-000000000000",
+			"azureClientId":            "00000000-0000-0000-0000-000000000000",
+			"azureCertificateFile":     certFile,
+			"azureCertificatePassword": "",
+		}
+	}
+
+	t.Run("valid certificate", func(t *testing.T) {
+		md := keyvaultMetadata{}
+		err := md.InitWithMetadata(contribCrypto.Metadata{Base: metadata.Base{Properties: props(writeTestCertificate(t))}})
+		require.NoError(t, err)
+		assert.NotNil(t, md.cred)
+	})
+
+	t.Run("file does not exist", func(t *testing.T) {
+		md := keyvaultMetadata{}
+		err := md.InitWithMetadata(contribCrypto.Metadata{Base: metadata.Base{Properties: props(filepath.Join(t.TempDir(), "missing.pem"))}})
+		require.Error(t, err)
+		assert.ErrorContains(t, err, "failed to load client certificate")
+		assert.ErrorContains(t, err, "failed to read the certificate file")
+	})
+
+	t.Run("invalid certificate or password", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "cert.pfx")
+		require.NoError(t, os.WriteFile(path, []byte("not a certificate"), 0o600))
+
+		md := keyvaultMetadata{}
+		err := md.InitWithMetadata(contribCrypto.Metadata{Base: metadata.Base{Properties: props(path)}})
+		require.Error(t, err)
+		assert.ErrorContains(t, err, "failed to load client certificate")
+	})
+}
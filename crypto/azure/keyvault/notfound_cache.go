@@ -0,0 +1,116 @@
+/*
+Copyright 2024 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keyvault
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"k8s.io/utils/clock"
+)
+
+// Maximum number of keys remembered by the notFoundCache.
+const defaultNotFoundCacheMaxSize = 1000
+
+// notFoundCache remembers keys that were not found in the vault for a short time, so requests for keys that don't exist do not hit the vault every time.
+type notFoundCache struct {
+	ttl   time.Duration
+	clock clock.Clock
+	// Map of key to expiration time
+	// The map holds up to maxItems keys, so callers requesting many different (including user-supplied) key names can't make it grow indefinitely
+	items    map[string]time.Time
+	maxItems int
+	lock     sync.Mutex
+}
+
+func newNotFoundCache(ttl time.Duration) *notFoundCache {
+	return &notFoundCache{
+		ttl:      ttl,
+		clock:    clock.RealClock{},
+		items:    make(map[string]time.Time),
+		maxItems: defaultNotFoundCacheMaxSize,
+	}
+}
+
+// IsNotFound returns true if the key was recently not found in the vault.
+func (c *notFoundCache) IsNotFound(kid keyID) bool {
+	if c.ttl <= 0 {
+		return false
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	key := kid.String()
+	exp, ok := c.items[key]
+	if !ok {
+		return false
+	}
+	if !c.clock.Now().Before(exp) {
+		delete(c.items, key)
+		return false
+	}
+	return true
+}
+
+// Record adds the key to the cache if the error returned by the vault indicates that the key was not found.
+func (c *notFoundCache) Record(kid keyID, err error) {
+	if c.ttl <= 0 || !isNotFoundError(err) {
+		return
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	key := kid.String()
+	now := c.clock.Now()
+	if _, ok := c.items[key]; !ok && len(c.items) >= c.maxItems {
+		c.evict(now)
+	}
+	c.items[key] = now.Add(c.ttl)
+}
+
+// Removes expired keys from the cache; if none has expired, removes the one that expires first.
+// Must be invoked while holding the lock.
+func (c *notFoundCache) evict(now time.Time) {
+	var (
+		oldestKey string
+		oldestExp time.Time
+	)
+	for k, exp := range c.items {
+		if !now.Before(exp) {
+			delete(c.items, k)
+			continue
+		}
+		if oldestKey == "" || exp.Before(oldestExp) {
+			oldestKey = k
+			oldestExp = exp
+		}
+	}
+	if len(c.items) >= c.maxItems && oldestKey != "" {
+		delete(c.items, oldestKey)
+	}
+}
+
+// Returns true if the error is a "not found" response from the vault.
+func isNotFoundError(err error) bool {
+	if errors.Is(err, errKeyNotFound) {
+		return true
+	}
+	var respErr *azcore.ResponseError
+	return errors.As(err, &respErr) && respErr.StatusCode == http.StatusNotFound
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2024 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keyvault
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+
+	contribCrypto "github.com/dapr/components-contrib/crypto"
+)
+
+// Delay before the first retry; this is the same as the default in the Azure SDK.
+const retryInitialInterval = 800 * time.Millisecond
+
+// vaultRequest invokes a request to the vault, retrying it in case of transient errors, including throttling.
+// The request timeout applies to the operation as a whole, including retries.
+func vaultRequest[T any](parentCtx context.Context, k *keyvaultCrypto, fn func(ctx context.Context) (T, error)) (T, error) {
+	ctx, cancel := context.WithTimeout(parentCtx, k.md.RequestTimeout)
+	defer cancel()
+
+	return contribCrypto.Retry(ctx, contribCrypto.RetryOptions{
+		MaxAttempts:     k.md.MaxRetries + 1,
+		InitialInterval: retryInitialInterval,
+		MaxInterval:     k.md.MaxRetryDelay,
+		IsRetryable:     isRetryableError,
+		RetryAfter:      retryAfter,
+		OnRetry: func(err error, delay time.Duration) {
+			k.logger.Debugf("Request to Key Vault failed, will retry in %v: %v", delay, err)
+		},
+	}, fn)
+}
+
+// isRetryableError returns true if the error returned by the vault is transient.
+// These are the same status codes that are retried by the Azure SDK by default.
+func isRetryableError(err error) bool {
+	var respErr *azcore.ResponseError
+	if !errors.As(err, &respErr) {
+		return false
+	}
+
+	switch respErr.StatusCode {
+	case http.StatusRequestTimeout,
+		http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// retryAfter returns the delay requested by the vault in the response headers, or 0 if there's none.
+func retryAfter(err error) time.Duration {
+	var respErr *azcore.ResponseError
+	if !errors.As(err, &respErr) || respErr.RawResponse == nil {
+		return 0
+	}
+
+	header := respErr.RawResponse.Header
+	for _, h := range []string{"Retry-After-Ms", "X-Ms-Retry-After-Ms"} {
+		if v := header.Get(h); v != "" {
+			ms, err := strconv.ParseInt(v, 10, 64)
+			if err == nil && ms > 0 {
+				return time.Duration(ms) * time.Millisecond
+			}
+		}
+	}
+
+	// The Retry-After header can contain a number of seconds or a date
+	if v := header.Get("Retry-After"); v != "" {
+		sec, err := strconv.ParseInt(v, 10, 64)
+		if err == nil && sec > 0 {
+			return time.Duration(sec) * time.Second
+		}
+		date, err := http.ParseTime(v)
+		if err == nil {
+			return time.Until(date)
+		}
+	}
+
+	return 0
+}
@@ -17,5 +17,46 @@ import (
 	"github.com/dapr/components-contrib/common/features"
 )
 
+const (
+	// FeatureOperationEncrypt is the feature for components that support the "encrypt" operation.
+	FeatureOperationEncrypt Feature = "OPERATION_ENCRYPT"
+	// FeatureOperationDecrypt is the feature for components that support the "decrypt" operation.
+	FeatureOperationDecrypt Feature = "OPERATION_DECRYPT"
+	// FeatureOperationWrapKey is the feature for components that support the "wrapKey" operation.
+	FeatureOperationWrapKey Feature = "OPERATION_WRAP_KEY"
+	// FeatureOperationUnwrapKey is the feature for components that support the "unwrapKey" operation.
+	FeatureOperationUnwrapKey Feature = "OPERATION_UNWRAP_KEY"
+	// FeatureOperationSign is the feature for components that support the "sign" operation.
+	FeatureOperationSign Feature = "OPERATION_SIGN"
+	// FeatureOperationVerify is the feature for components that support the "verify" operation.
+	FeatureOperationVerify Feature = "OPERATION_VERIFY"
+
+	// FeatureVaultOperations is the feature for components that perform operations with private and symmetric keys inside the vault, without the key material ever leaving it.
+	FeatureVaultOperations Feature = "VAULT_OPERATIONS"
+	// FeatureLocalPublicKeyOperations is the feature for components that can perform operations with public keys (encrypt, wrap, verify) locally, using keys cached from the vault.
+	FeatureLocalPublicKeyOperations Feature = "LOCAL_PUBLIC_KEY_OPERATIONS"
+)
+
 // Feature names a feature that can be implemented by the crypto provider components.
 type Feature = features.Feature[SubtleCrypto]
+
+// OperationFeatures returns the features for the operations that a crypto provider can perform.
+// Encrypt, wrapKey, and verify only require a public key, so they are always included; decrypt, unwrapKey, and sign require a private or symmetric key, so they are included only if withPrivateKeys is true.
+func OperationFeatures(withPrivateKeys bool) []Feature {
+	if !withPrivateKeys {
+		return []Feature{
+			FeatureOperationEncrypt,
+			FeatureOperationWrapKey,
+			FeatureOperationVerify,
+		}
+	}
+
+	return []Feature{
+		FeatureOperationEncrypt,
+		FeatureOperationDecrypt,
+		FeatureOperationWrapKey,
+		FeatureOperationUnwrapKey,
+		FeatureOperationSign,
+		FeatureOperationVerify,
+	}
+}
@@ -17,6 +17,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io/fs"
+	"os"
 	"reflect"
 	"sync"
 	"sync/atomic"
@@ -24,16 +26,24 @@ import (
 	"github.com/lestrrat-go/jwx/v2/jwk"
 
 	contribCrypto "github.com/dapr/components-contrib/crypto"
+	"github.com/dapr/components-contrib/health"
 	contribMetadata "github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/kit/jwkscache"
 	"github.com/dapr/kit/logger"
 )
 
+var (
+	// Returned when the JWKS location is a path to a directory rather than a file.
+	errJWKSPathIsDirectory = errors.New("jwks path points to a directory, expected a file")
+	// Returned when the JWKS location is a path to a file that cannot be read.
+	errJWKSFileUnreadable = errors.New("jwks path points to a file that cannot be read")
+)
+
 type jwksCrypto struct {
 	contribCrypto.LocalCryptoBaseComponent
 
 	md      jwksMetadata
-	cache   *jwkscache.JWKSCache
+	keys    keySource
 	logger  logger.Logger
 	closed  atomic.Bool
 	closeCh chan struct{}
@@ -63,26 +73,79 @@ func (k *jwksCrypto) Init(ctx context.Context, metadata contribCrypto.Metadata)
 		return fmt.Errorf("failed to load metadata: %w", err)
 	}
 
+	// Load a single JWKS
+	if len(k.md.sources) == 0 {
+		k.keys, err = k.newKeySource(ctx, k.md.JWKS)
+		return err
+	}
+
+	// Load all JWKS and merge them
+	merged := &mergedSource{
+		sources: make([]keySource, len(k.md.sources)),
+		logger:  k.logger,
+	}
+	for i, location := range k.md.sources {
+		merged.sources[i], err = k.newKeySource(ctx, location)
+		if err != nil {
+			return fmt.Errorf("failed to load JWKS source %d: %w", i, err)
+		}
+	}
+
+	// Check that there are no conflicting keys
+	_, err = merged.merge()
+	if err != nil {
+		return err
+	}
+	k.keys = merged
+
+	return nil
+}
+
+// Returns a keySource for the JWKS at the given location, which can be a URL, a path to a local file, or the JWKS itself.
+func (k *jwksCrypto) newKeySource(ctx context.Context, location string) (keySource, error) {
+	// JWKS fetched from a URL are managed by the component, so the refresh interval can be honored
+	if isURL(location) {
+		return newURLSource(ctx, k.getContext(), location, k.md, k.logger)
+	}
+
+	// Check if the location is a path to a local file
+	stat, err := os.Stat(location)
+	switch {
+	case err == nil && stat.IsDir():
+		return nil, errJWKSPathIsDirectory
+	case err == nil:
+		src := newFileSource(location, k.md.FileRefreshInterval, k.logger)
+		err = src.Start(k.getContext())
+		if err != nil {
+			return nil, err
+		}
+		return src, nil
+	case errors.Is(err, fs.ErrPermission):
+		return nil, fmt.Errorf("%w: %w", errJWKSFileUnreadable, err)
+	}
+
+	// Treat the location as the actual JWKS
+
 	// Init the JWKS cache
-	k.cache = jwkscache.NewJWKSCache(k.md.JWKS, k.logger)
-	k.cache.SetMinRefreshInterval(k.md.MinRefreshInterval)
-	k.cache.SetRequestTimeout(k.md.RequestTimeout)
+	cache := jwkscache.NewJWKSCache(location, k.logger)
+	cache.SetMinRefreshInterval(k.md.MinRefreshInterval)
+	cache.SetRequestTimeout(k.md.RequestTimeout)
 
 	// Start the JWKS cache in background
 	startErrCh := make(chan error)
 	go func() {
-		startErrCh <- k.cache.Start(k.getContext())
+		startErrCh <- cache.Start(k.getContext())
 	}()
 
 	// Wait for the cache to be ready
 	// Here we use the init context
-	err = k.cache.WaitForCacheReady(ctx)
+	err = cache.WaitForCacheReady(ctx)
 	if err != nil {
 		// If we have an initialization error, return
-		return err
+		return nil, err
 	}
 
-	return nil
+	return cache, nil
 }
 
 // Returns a context that is canceled when the component is closed.
@@ -108,13 +171,55 @@ func (k *jwksCrypto) Close() error {
 }
 
 // Features returns the features available in this crypto provider.
+// Operations that require a private or symmetric key are included only if the JWKS currently contains at least one such key.
 func (k *jwksCrypto) Features() []contribCrypto.Feature {
-	return []contribCrypto.Feature{} // No Feature supported.
+	return contribCrypto.OperationFeatures(k.hasPrivateKeys())
+}
+
+// Returns true if the JWKS contains at least one private or symmetric key.
+func (k *jwksCrypto) hasPrivateKeys() bool {
+	if k.keys == nil {
+		return false
+	}
+	jwks := k.keys.KeySet()
+	if jwks == nil {
+		return false
+	}
+
+	for i := 0; i < jwks.Len(); i++ {
+		key, ok := jwks.Key(i)
+		if ok && !contribCrypto.IsPublicOnlyKey(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// Ping returns an error if the component doesn't have any key loaded.
+// It also returns an error if the last refresh of a JWKS fetched from a URL failed, or if a JWKS file doesn't exist anymore or couldn't be parsed.
+// Implements the health.Pinger interface.
+func (k *jwksCrypto) Ping(ctx context.Context) error {
+	if k.keys == nil {
+		return errors.New("no JWKS loaded")
+	}
+
+	if pinger, ok := k.keys.(health.Pinger); ok {
+		err := pinger.Ping(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	jwks := k.keys.KeySet()
+	if jwks == nil || jwks.Len() == 0 {
+		return errors.New("the JWKS does not contain any key")
+	}
+	return nil
 }
 
 // Retrieves a key (public or private or symmetric) from the JWKS
 func (k *jwksCrypto) retrieveKeyFromSecretFn(parentCtx context.Context, kid string) (jwk.Key, error) {
-	jwks := k.cache.KeySet()
+	jwks := k.keys.KeySet()
 	if jwks == nil {
 		return nil, errors.New("no JWKS loaded")
 	}
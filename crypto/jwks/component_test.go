@@ -0,0 +1,395 @@
+/*
+Copyright 2024 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jwks
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	contribCrypto "github.com/dapr/components-contrib/crypto"
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/kit/logger"
+)
+
+// Returns a JWKS with a single symmetric key with the given ID.
+func testSymmetricJWKS(t *testing.T, kid string) string {
+	t.Helper()
+
+	key, err := jwk.FromRaw([]byte("0123456789abcdef0123456789abcdef"))
+	require.NoError(t, err)
+	require.NoError(t, key.Set(jwk.KeyIDKey, kid))
+
+	set := jwk.NewSet()
+	require.NoError(t, set.AddKey(key))
+	enc, err := json.Marshal(set)
+	require.NoError(t, err)
+	return string(enc)
+}
+
+// Returns a JWKS with a private RSA key with ID "private", and its public part with ID "public".
+func testPrivateAndPublicJWKS(t *testing.T) string {
+	t.Helper()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	privateKey, err := jwk.FromRaw(rsaKey)
+	require.NoError(t, err)
+	require.NoError(t, privateKey.Set(jwk.KeyIDKey, "private"))
+
+	publicKey, err := privateKey.PublicKey()
+	require.NoError(t, err)
+	require.NoError(t, publicKey.Set(jwk.KeyIDKey, "public"))
+
+	set := jwk.NewSet()
+	require.NoError(t, set.AddKey(privateKey))
+	require.NoError(t, set.AddKey(publicKey))
+	enc, err := json.Marshal(set)
+	require.NoError(t, err)
+	return string(enc)
+}
+
+func initTestComponent(t *testing.T, props map[string]string) *jwksCrypto {
+	t.Helper()
+
+	k := NewJWKSCrypto(logger.NewLogger("test")).(*jwksCrypto)
+	err := k.Init(context.Background(), contribCrypto.Metadata{Base: metadata.Base{Properties: props}})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		k.Close()
+	})
+	return k
+}
+
+func TestPrivateAndPublicKeys(t *testing.T) {
+	k := initTestComponent(t, map[string]string{
+		"jwks": testPrivateAndPublicJWKS(t),
+	})
+	ctx := context.Background()
+
+	digest := sha256.Sum256([]byte("message"))
+
+	t.Run("sign with private key", func(t *testing.T) {
+		signature, err := k.Sign(ctx, digest[:], "PS256", "private")
+		require.NoError(t, err)
+
+		// The signature can be verified with both keys
+		valid, err := k.Verify(ctx, digest[:], signature, "PS256", "private")
+		require.NoError(t, err)
+		assert.True(t, valid)
+		valid, err = k.Verify(ctx, digest[:], signature, "PS256", "public")
+		require.NoError(t, err)
+		assert.True(t, valid)
+	})
+
+	t.Run("sign with public-only key fails", func(t *testing.T) {
+		_, err := k.Sign(ctx, digest[:], "PS256", "public")
+		require.ErrorIs(t, err, contribCrypto.ErrKeyPublicOnly)
+	})
+
+	t.Run("encrypt with public key and decrypt with private key", func(t *testing.T) {
+		ciphertext, _, err := k.Encrypt(ctx, []byte("secret"), "RSA-OAEP-256", "public", nil, nil)
+		require.NoError(t, err)
+
+		plaintext, err := k.Decrypt(ctx, ciphertext, "RSA-OAEP-256", "private", nil, nil, nil)
+		require.NoError(t, err)
+		assert.Equal(t, []byte("secret"), plaintext)
+
+		_, err = k.Decrypt(ctx, ciphertext, "RSA-OAEP-256", "public", nil, nil, nil)
+		require.ErrorIs(t, err, contribCrypto.ErrKeyPublicOnly)
+	})
+
+	t.Run("unwrap with public-only key fails", func(t *testing.T) {
+		aesKey, err := jwk.FromRaw([]byte("0123456789abcdef0123456789abcdef"))
+		require.NoError(t, err)
+
+		wrapped, _, err := k.WrapKey(ctx, aesKey, "RSA-OAEP-256", "public", nil, nil)
+		require.NoError(t, err)
+
+		unwrapped, err := k.UnwrapKey(ctx, wrapped, "RSA-OAEP-256", "private", nil, nil, nil)
+		require.NoError(t, err)
+		assert.True(t, jwk.Equal(aesKey, unwrapped))
+
+		_, err = k.UnwrapKey(ctx, wrapped, "RSA-OAEP-256", "public", nil, nil, nil)
+		require.ErrorIs(t, err, contribCrypto.ErrKeyPublicOnly)
+	})
+}
+
+func TestFeatures(t *testing.T) {
+	allOperations := []contribCrypto.Feature{
+		contribCrypto.FeatureOperationEncrypt,
+		contribCrypto.FeatureOperationDecrypt,
+		contribCrypto.FeatureOperationWrapKey,
+		contribCrypto.FeatureOperationUnwrapKey,
+		contribCrypto.FeatureOperationSign,
+		contribCrypto.FeatureOperationVerify,
+	}
+
+	t.Run("JWKS with private keys", func(t *testing.T) {
+		k := initTestComponent(t, map[string]string{
+			"jwks": testPrivateAndPublicJWKS(t),
+		})
+		assert.ElementsMatch(t, allOperations, k.Features())
+	})
+
+	t.Run("JWKS with symmetric keys", func(t *testing.T) {
+		k := initTestComponent(t, map[string]string{
+			"jwks": testSymmetricJWKS(t, "key"),
+		})
+		assert.ElementsMatch(t, allOperations, k.Features())
+	})
+
+	t.Run("JWKS with public keys only", func(t *testing.T) {
+		rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+		publicKey, err := jwk.FromRaw(rsaKey.Public())
+		require.NoError(t, err)
+		require.NoError(t, publicKey.Set(jwk.KeyIDKey, "public"))
+		set := jwk.NewSet()
+		require.NoError(t, set.AddKey(publicKey))
+		enc, err := json.Marshal(set)
+		require.NoError(t, err)
+
+		k := initTestComponent(t, map[string]string{
+			"jwks": string(enc),
+		})
+		assert.ElementsMatch(t, []contribCrypto.Feature{
+			contribCrypto.FeatureOperationEncrypt,
+			contribCrypto.FeatureOperationWrapKey,
+			contribCrypto.FeatureOperationVerify,
+		}, k.Features())
+	})
+}
+
+func TestJWKSFromURL(t *testing.T) {
+	jwks := testPrivateAndPublicJWKS(t)
+
+	t.Run("refreshes with the configured interval", func(t *testing.T) {
+		var requests atomic.Int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests.Add(1)
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(jwks))
+		}))
+		defer srv.Close()
+
+		k := initTestComponent(t, map[string]string{
+			"jwks":               srv.URL,
+			"minRefreshInterval": "1s",
+		})
+
+		// The JWKS is fetched during Init
+		assert.GreaterOrEqual(t, requests.Load(), int32(1))
+		_, err := k.retrieveKeyFromSecretFn(context.Background(), "private")
+		require.NoError(t, err)
+
+		// The JWKS should be refreshed after about 1s
+		assert.Eventually(t, func() bool {
+			return requests.Load() >= 3
+		}, 5*time.Second, 100*time.Millisecond)
+	})
+
+	t.Run("init fails fast when the URL is unreachable", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(5 * time.Second):
+			}
+		}))
+		defer srv.Close()
+
+		k := NewJWKSCrypto(logger.NewLogger("test")).(*jwksCrypto)
+		defer k.Close()
+
+		start := time.Now()
+		err := k.Init(context.Background(), contribCrypto.Metadata{Base: metadata.Base{Properties: map[string]string{
+			"jwks":           srv.URL,
+			"requestTimeout": "200ms",
+		}}})
+		require.Error(t, err)
+		assert.Less(t, time.Since(start), 2*time.Second)
+	})
+}
+
+func TestMultipleSources(t *testing.T) {
+	// Write a JWKS to a file
+	file := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(file, []byte(testPrivateAndPublicJWKS(t)), 0o600))
+
+	sourcesJSON := func(t *testing.T, sources ...string) string {
+		enc, err := json.Marshal(sources)
+		require.NoError(t, err)
+		return string(enc)
+	}
+
+	t.Run("merges inline and file sources", func(t *testing.T) {
+		k := initTestComponent(t, map[string]string{
+			"jwksSources": sourcesJSON(t, testSymmetricJWKS(t, "symmetric"), file),
+		})
+
+		for _, kid := range []string{"symmetric", "private", "public"} {
+			key, err := k.retrieveKeyFromSecretFn(context.Background(), kid)
+			require.NoErrorf(t, err, "key %s not found", kid)
+			assert.Equal(t, kid, key.KeyID())
+		}
+
+		_, err := k.retrieveKeyFromSecretFn(context.Background(), "notfound")
+		require.ErrorIs(t, err, contribCrypto.ErrKeyNotFound)
+	})
+
+	t.Run("duplicate key IDs cause an error", func(t *testing.T) {
+		k := NewJWKSCrypto(logger.NewLogger("test")).(*jwksCrypto)
+		defer k.Close()
+
+		err := k.Init(context.Background(), contribCrypto.Metadata{Base: metadata.Base{Properties: map[string]string{
+			"jwksSources": sourcesJSON(t, testSymmetricJWKS(t, "private"), file),
+		}}})
+		require.Error(t, err)
+		assert.ErrorContains(t, err, "duplicate key ID 'private'")
+	})
+
+	t.Run("cannot be used with jwks", func(t *testing.T) {
+		k := NewJWKSCrypto(logger.NewLogger("test")).(*jwksCrypto)
+		defer k.Close()
+
+		err := k.Init(context.Background(), contribCrypto.Metadata{Base: metadata.Base{Properties: map[string]string{
+			"jwks":        file,
+			"jwksSources": sourcesJSON(t, file),
+		}}})
+		require.Error(t, err)
+	})
+}
+
+func TestInvalidPaths(t *testing.T) {
+	initErr := func(t *testing.T, jwks string) error {
+		k := NewJWKSCrypto(logger.NewLogger("test")).(*jwksCrypto)
+		defer k.Close()
+
+		return k.Init(context.Background(), contribCrypto.Metadata{Base: metadata.Base{Properties: map[string]string{
+			"jwks": jwks,
+		}}})
+	}
+
+	t.Run("path is a directory", func(t *testing.T) {
+		err := initErr(t, t.TempDir())
+		require.ErrorIs(t, err, errJWKSPathIsDirectory)
+	})
+
+	t.Run("file is not readable", func(t *testing.T) {
+		if runtime.GOOS == "windows" || os.Geteuid() == 0 {
+			t.Skip("File permissions cannot be tested on Windows or as root")
+		}
+
+		file := filepath.Join(t.TempDir(), "jwks.json")
+		require.NoError(t, os.WriteFile(file, []byte(testSymmetricJWKS(t, "key")), 0o000))
+
+		err := initErr(t, file)
+		require.ErrorIs(t, err, errJWKSFileUnreadable)
+	})
+}
+
+func TestPing(t *testing.T) {
+	t.Run("loaded key set", func(t *testing.T) {
+		k := initTestComponent(t, map[string]string{
+			"jwks": testSymmetricJWKS(t, "key"),
+		})
+		require.NoError(t, k.Ping(context.Background()))
+	})
+
+	t.Run("empty key set", func(t *testing.T) {
+		k := initTestComponent(t, map[string]string{
+			"jwks": `{"keys":[]}`,
+		})
+		require.ErrorContains(t, k.Ping(context.Background()), "does not contain any key")
+	})
+
+	t.Run("failed refresh from URL", func(t *testing.T) {
+		var failing atomic.Bool
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if failing.Load() {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(testSymmetricJWKS(t, "key")))
+		}))
+		defer srv.Close()
+
+		k := initTestComponent(t, map[string]string{
+			"jwks":               srv.URL,
+			"minRefreshInterval": "1s",
+		})
+		require.NoError(t, k.Ping(context.Background()))
+
+		// After a refresh fails, Ping returns an error
+		failing.Store(true)
+		assert.Eventually(t, func() bool {
+			err := k.Ping(context.Background())
+			return err != nil && strings.Contains(err.Error(), "last refresh of the JWKS failed")
+		}, 5*time.Second, 100*time.Millisecond)
+
+		// The error is cleared after the next successful refresh
+		failing.Store(false)
+		assert.Eventually(t, func() bool {
+			return k.Ping(context.Background()) == nil
+		}, 5*time.Second, 100*time.Millisecond)
+	})
+
+	t.Run("file removed", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "jwks.json")
+		require.NoError(t, os.WriteFile(file, []byte(testSymmetricJWKS(t, "key")), 0o600))
+
+		k := initTestComponent(t, map[string]string{
+			"jwks": file,
+		})
+		require.NoError(t, k.Ping(context.Background()))
+
+		require.NoError(t, os.Remove(file))
+		require.ErrorIs(t, k.Ping(context.Background()), errJWKSFileUnreadable)
+	})
+
+	t.Run("file source in merged sources fails to parse", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "jwks.json")
+		require.NoError(t, os.WriteFile(file, []byte(testSymmetricJWKS(t, "key")), 0o600))
+
+		k := initTestComponent(t, map[string]string{
+			"jwksSources":         `[` + strconv.Quote(testSymmetricJWKS(t, "inline")) + `,` + strconv.Quote(file) + `]`,
+			"fileRefreshInterval": "100ms",
+		})
+		require.NoError(t, k.Ping(context.Background()))
+
+		require.NoError(t, os.WriteFile(file, []byte("not a jwks"), 0o600))
+		assert.Eventually(t, func() bool {
+			err := k.Ping(context.Background())
+			return err != nil && strings.Contains(err.Error(), "JWKS source 1: failed to parse JWKS file")
+		}, 5*time.Second, 50*time.Millisecond)
+	})
+}
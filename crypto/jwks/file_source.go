@@ -0,0 +1,198 @@
+/*
+Copyright 2024 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jwks
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"k8s.io/utils/clock"
+
+	"github.com/dapr/kit/fswatcher"
+	"github.com/dapr/kit/logger"
+)
+
+// fileSource is a keySource for a JWKS stored in a local file.
+// The file is reloaded when the filesystem reports a change to the folder containing it and, if refreshInterval is set, periodically.
+type fileSource struct {
+	path            string
+	refreshInterval time.Duration
+	logger          logger.Logger
+	clock           clock.WithTicker
+
+	// Watches for changes to the folder and sends a notification to eventCh.
+	// Can be replaced in tests.
+	watchFn func(ctx context.Context, dir string, eventCh chan<- struct{}) error
+
+	jwks    jwk.Set
+	lastRaw []byte
+	loadErr error
+	lock    sync.RWMutex
+}
+
+func newFileSource(path string, refreshInterval time.Duration, logger logger.Logger) *fileSource {
+	return &fileSource{
+		path:            path,
+		refreshInterval: refreshInterval,
+		logger:          logger,
+		clock:           clock.RealClock{},
+		watchFn:         watchFolder,
+	}
+}
+
+// Start loads the JWKS file, then keeps reloading it in background until ctx is canceled.
+// Returns an error if the file can't be loaded the first time.
+func (s *fileSource) Start(ctx context.Context) error {
+	err := s.reload()
+	if err != nil {
+		return err
+	}
+
+	// Reloads are requested by sending a message to reloadCh
+	// The channel has a buffer of 1 and messages are sent without blocking, so requests that come in while a reload is pending are coalesced
+	reloadCh := make(chan struct{}, 1)
+	requestReload := func() {
+		select {
+		case reloadCh <- struct{}{}:
+		default:
+		}
+	}
+
+	// Watch for changes in the filesystem
+	eventCh := make(chan struct{})
+	go func() {
+		// Log errors only
+		err := s.watchFn(ctx, filepath.Dir(s.path), eventCh)
+		if err != nil {
+			s.logger.Errorf("Error while watching for changes to the local JWKS file: %v", err)
+		}
+	}()
+
+	// If we have a refresh interval, also poll the file periodically
+	// This is useful on filesystems that don't emit events, such as NFS
+	var tickCh <-chan time.Time
+	if s.refreshInterval > 0 {
+		ticker := s.clock.NewTicker(s.refreshInterval)
+		tickCh = ticker.C()
+		go func() {
+			<-ctx.Done()
+			ticker.Stop()
+		}()
+	}
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-eventCh:
+				requestReload()
+			case <-tickCh:
+				requestReload()
+			}
+		}
+	}()
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-reloadCh:
+				err := s.reload()
+				if err != nil {
+					// Log errors only, and keep the previous JWKS
+					s.logger.Errorf("Error reading JWKS from disk: %v", err)
+				}
+			}
+		}
+	}()
+
+	return nil
+}
+
+// KeySet implements keySource.
+func (s *fileSource) KeySet() jwk.Set {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.jwks
+}
+
+// Ping implements health.Pinger.
+// It returns an error if the file doesn't exist anymore, or if the last time it was loaded, it couldn't be read or parsed.
+func (s *fileSource) Ping(_ context.Context) error {
+	_, err := os.Stat(s.path)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errJWKSFileUnreadable, err)
+	}
+
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.loadErr
+}
+
+// Reads the JWKS file and parses it if it has changed, and records the result.
+func (s *fileSource) reload() error {
+	err := s.load()
+	s.lock.Lock()
+	s.loadErr = err
+	s.lock.Unlock()
+	return err
+}
+
+// Reads the JWKS file and parses it if it has changed.
+func (s *fileSource) load() error {
+	read, err := os.ReadFile(s.path)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errJWKSFileUnreadable, err)
+	}
+
+	// Skip parsing the file if it hasn't changed since the last time
+	s.lock.RLock()
+	unchanged := s.jwks != nil && bytes.Equal(read, s.lastRaw)
+	s.lock.RUnlock()
+	if unchanged {
+		return nil
+	}
+
+	s.logger.Debug("Loading JWKS file from disk")
+	jwks, err := jwk.Parse(read)
+	if err != nil {
+		return fmt.Errorf("failed to parse JWKS file: %w", err)
+	}
+
+	s.lock.Lock()
+	s.jwks = jwks
+	s.lastRaw = read
+	s.lock.Unlock()
+
+	return nil
+}
+
+// Watches for changes to the folder using fsnotify.
+func watchFolder(ctx context.Context, dir string, eventCh chan<- struct{}) error {
+	fw, err := fswatcher.New(fswatcher.Options{
+		Targets: []string{dir},
+	})
+	if err != nil {
+		return err
+	}
+	return fw.Run(ctx, eventCh)
+}
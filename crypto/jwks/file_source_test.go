@@ -0,0 +1,63 @@
+/*
+Copyright 2024 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jwks
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	clocktesting "k8s.io/utils/clock/testing"
+
+	"github.com/dapr/kit/logger"
+)
+
+func TestFileSourcePolling(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(file, []byte(testSymmetricJWKS(t, "key1")), 0o600))
+
+	clock := clocktesting.NewFakeClock(time.Now())
+	src := newFileSource(file, time.Minute, logger.NewLogger("test"))
+	src.clock = clock
+
+	// Simulate a filesystem that never emits events
+	src.watchFn = func(ctx context.Context, dir string, eventCh chan<- struct{}) error {
+		<-ctx.Done()
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(t, src.Start(ctx))
+
+	_, found := src.KeySet().LookupKeyID("key1")
+	require.True(t, found)
+
+	// Rotate the key
+	require.NoError(t, os.WriteFile(file, []byte(testSymmetricJWKS(t, "key2")), 0o600))
+
+	// Nothing changes until the refresh interval has passed
+	_, found = src.KeySet().LookupKeyID("key2")
+	assert.False(t, found)
+
+	clock.Step(time.Minute)
+	assert.Eventually(t, func() bool {
+		_, found := src.KeySet().LookupKeyID("key2")
+		return found
+	}, 5*time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,150 @@
+/*
+Copyright 2024 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jwks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/lestrrat-go/jwx/v2/jwk"
+
+	"github.com/dapr/components-contrib/health"
+	"github.com/dapr/kit/logger"
+)
+
+// mergedSource is a keySource that combines the keys from multiple sources.
+// Each source is refreshed independently, and keys are merged again only after a source returns a different set.
+type mergedSource struct {
+	sources []keySource
+	logger  logger.Logger
+
+	// Merged set, and the sets from each source it was built from
+	merged     jwk.Set
+	sourceSets []jwk.Set
+	// Conflicts that have already been logged
+	loggedConflicts map[string]struct{}
+	lock            sync.Mutex
+}
+
+// KeySet implements keySource.
+// If a source is updated and it contains a key ID that is already present in another source, the key from the source listed first is used.
+func (s *mergedSource) KeySet() jwk.Set {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	// Sources return a new set object every time they are refreshed, so if none has changed, we can return the set we merged last
+	sets := make([]jwk.Set, len(s.sources))
+	changed := s.merged == nil
+	for i, src := range s.sources {
+		sets[i] = src.KeySet()
+		if !changed && sets[i] != s.sourceSets[i] {
+			changed = true
+		}
+	}
+	if !changed {
+		return s.merged
+	}
+
+	merged, conflicts, err := mergeSets(sets)
+	if err != nil {
+		// Keep the previous set, and try again the next time
+		s.logger.Errorf("Failed to merge JWKS sources: %v", err)
+		return s.merged
+	}
+
+	// Each conflict is logged only the first time it's found
+	if s.loggedConflicts == nil {
+		s.loggedConflicts = make(map[string]struct{})
+	}
+	for _, conflict := range conflicts {
+		msg := conflict.Error()
+		if _, ok := s.loggedConflicts[msg]; ok {
+			continue
+		}
+		s.loggedConflicts[msg] = struct{}{}
+		s.logger.Warnf("Conflict while merging JWKS sources, keeping the first key: %v", conflict)
+	}
+
+	s.merged = merged
+	s.sourceSets = sets
+	return merged
+}
+
+// Ping implements health.Pinger.
+// It returns an error if any of the sources reports one.
+func (s *mergedSource) Ping(ctx context.Context) error {
+	errs := make([]error, 0)
+	for i, src := range s.sources {
+		pinger, ok := src.(health.Pinger)
+		if !ok {
+			continue
+		}
+		err := pinger.Ping(ctx)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("JWKS source %d: %w", i, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Merges the keys from all sources into a new set.
+// Returns an error if the same key ID is found more than once, together with the set that contains the first occurrence of each key only.
+func (s *mergedSource) merge() (jwk.Set, error) {
+	sets := make([]jwk.Set, len(s.sources))
+	for i, src := range s.sources {
+		sets[i] = src.KeySet()
+	}
+	res, conflicts, err := mergeSets(sets)
+	if err != nil {
+		return nil, err
+	}
+	return res, errors.Join(conflicts...)
+}
+
+// Merges the keys from the sets into a new set, keeping the first occurrence of each key ID.
+// Returns the list of key IDs that were found more than once as conflicts.
+func mergeSets(sets []jwk.Set) (jwk.Set, []error, error) {
+	res := jwk.NewSet()
+	var conflicts []error
+	for i, set := range sets {
+		if set == nil {
+			continue
+		}
+
+		for j := 0; j < set.Len(); j++ {
+			key, ok := set.Key(j)
+			if !ok {
+				continue
+			}
+
+			kid := key.KeyID()
+			if kid != "" {
+				_, found := res.LookupKeyID(kid)
+				if found {
+					conflicts = append(conflicts, fmt.Errorf("duplicate key ID '%s' found in JWKS source %d", kid, i))
+					continue
+				}
+			}
+
+			err := res.AddKey(key)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to add key from JWKS source %d: %w", i, err)
+			}
+		}
+	}
+
+	return res, conflicts, nil
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2024 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jwks
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/kit/logger"
+)
+
+// staticSource is a keySource whose set can be replaced in tests.
+type staticSource struct {
+	set  jwk.Set
+	lock sync.Mutex
+}
+
+func (s *staticSource) KeySet() jwk.Set {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.set
+}
+
+func (s *staticSource) setJWKS(t *testing.T, jwks string) {
+	t.Helper()
+	set, err := jwk.ParseString(jwks)
+	require.NoError(t, err)
+	s.lock.Lock()
+	s.set = set
+	s.lock.Unlock()
+}
+
+func TestMergedSource(t *testing.T) {
+	var logs bytes.Buffer
+	log := logger.NewLogger("test")
+	log.SetOutput(&logs)
+
+	src1 := &staticSource{}
+	src1.setJWKS(t, testSymmetricJWKS(t, "key1"))
+	src2 := &staticSource{}
+	src2.setJWKS(t, testSymmetricJWKS(t, "key2"))
+	s := &mergedSource{
+		sources: []keySource{src1, src2},
+		logger:  log,
+	}
+
+	// The merged set is reused until a source changes
+	set := s.KeySet()
+	assert.Equal(t, 2, set.Len())
+	assert.Same(t, set, s.KeySet())
+
+	src2.setJWKS(t, testSymmetricJWKS(t, "key3"))
+	updated := s.KeySet()
+	assert.NotSame(t, set, updated)
+	_, found := updated.LookupKeyID("key3")
+	assert.True(t, found)
+	assert.Same(t, updated, s.KeySet())
+
+	// Conflicts are logged only once, even if the sources are refreshed
+	for i := 0; i < 3; i++ {
+		src2.setJWKS(t, testSymmetricJWKS(t, "key1"))
+		set = s.KeySet()
+		assert.Equal(t, 1, set.Len())
+	}
+	assert.Equal(t, 1, strings.Count(logs.String(), "duplicate key ID 'key1'"))
+}
@@ -14,7 +14,9 @@ limitations under the License.
 package jwks
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
 	"time"
 
 	contribCrypto "github.com/dapr/components-contrib/crypto"
@@ -31,15 +33,26 @@ type jwksMetadata struct {
 	// - The actual JWKS as a JSON-encoded string (optionally encoded with Base64-standard).
 	// - A URL to a HTTP(S) endpoint returning the JWKS.
 	// - A path to a local file containing the JWKS.
-	// Required.
+	// Required, unless jwksSources is set.
 	JWKS string `json:"jwks" mapstructure:"jwks"`
+	// List of JWKS to load, as a JSON array of strings; each item can be any of the values accepted by jwks.
+	// Keys from all sources are merged, and key IDs must be unique across all sources.
+	// Cannot be used together with jwks.
+	JWKSSources string `json:"jwksSources" mapstructure:"jwksSources"`
 	// Timeout for network requests, as a Go duration string (e.g. "30s")
 	// Defaults to "30s".
 	RequestTimeout time.Duration `json:"requestTimeout" mapstructure:"requestTimeout"`
 	// Minimum interval before the JWKS is refreshed, as a Go duration string.
-	// Only applies when the JWKS is fetched from a HTTP(S) URL.
+	// Only applies when the JWKS is fetched from a HTTP(S) URL; the JWKS may be refreshed less frequently if the server's caching headers require so.
 	// Defaults to "10m".
 	MinRefreshInterval time.Duration `json:"minRefreshInterval" mapstructure:"minRefreshInterval"`
+	// Interval for re-reading the JWKS from disk, as a Go duration string.
+	// Only applies when the JWKS is loaded from a local file, which is also reloaded when changes are detected; this is useful on filesystems that don't emit change events, such as NFS.
+	// Defaults to "0", which disables polling.
+	FileRefreshInterval time.Duration `json:"fileRefreshInterval" mapstructure:"fileRefreshInterval"`
+
+	// Internal properties
+	sources []string
 }
 
 func (m *jwksMetadata) InitWithMetadata(meta contribCrypto.Metadata) error {
@@ -51,8 +64,24 @@ func (m *jwksMetadata) InitWithMetadata(meta contribCrypto.Metadata) error {
 		return err
 	}
 
-	// Require the JWKS property to not be empty (further validation will be performed by the component)
-	if m.JWKS == "" {
+	// Require one of the JWKS and JWKSSources properties to not be empty (further validation will be performed by the component)
+	switch {
+	case m.JWKS != "" && m.JWKSSources != "":
+		return errors.New("metadata properties 'jwks' and 'jwksSources' cannot be used together")
+	case m.JWKSSources != "":
+		err = json.Unmarshal([]byte(m.JWKSSources), &m.sources)
+		if err != nil {
+			return fmt.Errorf("metadata property 'jwksSources' is not a valid JSON array of strings: %w", err)
+		}
+		if len(m.sources) == 0 {
+			return errors.New("metadata property 'jwksSources' must contain at least one item")
+		}
+		for i, v := range m.sources {
+			if v == "" {
+				return fmt.Errorf("metadata property 'jwksSources' contains an empty item at index %d", i)
+			}
+		}
+	case m.JWKS == "":
 		return errors.New("metadata property 'jwks' is required")
 	}
 
@@ -70,6 +99,9 @@ func (m *jwksMetadata) InitWithMetadata(meta contribCrypto.Metadata) error {
 // Reset the object
 func (m *jwksMetadata) reset() {
 	m.JWKS = ""
+	m.JWKSSources = ""
+	m.sources = nil
 	m.RequestTimeout = defaultRequestTimeout
 	m.MinRefreshInterval = defaultMinRefreshInterval
+	m.FileRefreshInterval = 0
 }
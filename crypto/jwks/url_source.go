@@ -0,0 +1,140 @@
+/*
+Copyright 2024 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jwks
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lestrrat-go/httprc"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+
+	"github.com/dapr/kit/logger"
+)
+
+// Maximum interval between checks for JWKS that need to be refreshed.
+const maxRefreshWindow = 15 * time.Minute
+
+// keySource is implemented by objects that provide a JWKS, which may be updated over time.
+type keySource interface {
+	// KeySet returns the current JWKS.
+	KeySet() jwk.Set
+}
+
+// urlSource is a keySource for a JWKS fetched from a HTTP(S) URL and refreshed in background.
+// The refresh is scheduled using the minRefreshInterval metadata option and the caching headers in the response, whichever is longer.
+type urlSource struct {
+	cache *jwk.Cache
+	url   string
+	log   logger.Logger
+
+	// Error returned by the last refresh, or nil if it succeeded
+	refreshErr error
+	lock       sync.RWMutex
+}
+
+// Returns true if the location is a HTTP(S) URL.
+func isURL(location string) bool {
+	return strings.HasPrefix(location, "https://") || strings.HasPrefix(location, "http://")
+}
+
+// Creates a new urlSource and fetches the JWKS, failing if that can't be done within the request timeout.
+// The background refreshes are stopped when runCtx is canceled.
+func newURLSource(initCtx context.Context, runCtx context.Context, url string, md jwksMetadata, log logger.Logger) (*urlSource, error) {
+	if strings.HasPrefix(url, "http://") {
+		log.Warn("Loading JWK from an HTTP endpoint without TLS: this is not recommended on production environments.")
+	}
+
+	s := &urlSource{
+		url: url,
+		log: log,
+	}
+
+	// The refresh window is the interval between checks for refreshes, so it must not be longer than the minimum refresh interval
+	cache := jwk.NewCache(runCtx,
+		jwk.WithRefreshWindow(min(md.MinRefreshInterval, maxRefreshWindow)),
+		jwk.WithErrSink(httprc.ErrSinkFunc(func(err error) {
+			log.Warnf("Error while refreshing JWKS cache: %v", err)
+			s.setRefreshErr(err)
+		})),
+	)
+
+	// Use a HTTP client with a timeout, as the default one doesn't have any
+	client := &http.Client{
+		Timeout: md.RequestTimeout,
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{
+				MinVersion: tls.VersionTLS12,
+			},
+		},
+	}
+
+	err := cache.Register(url,
+		jwk.WithMinRefreshInterval(md.MinRefreshInterval),
+		jwk.WithHTTPClient(client),
+		// Invoked after every successful fetch
+		jwk.WithPostFetcher(jwk.PostFetchFunc(func(_ string, set jwk.Set) (jwk.Set, error) {
+			s.setRefreshErr(nil)
+			return set, nil
+		})),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to register JWKS cache: %w", err)
+	}
+
+	// Fetch the JWKS right away, so we can fail fast if it's not reachable or not valid
+	refreshCtx, refreshCancel := context.WithTimeout(initCtx, md.RequestTimeout)
+	_, err = cache.Refresh(refreshCtx, url)
+	refreshCancel()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+
+	s.cache = cache
+	return s, nil
+}
+
+// KeySet implements keySource.
+// It returns the set currently stored in the cache, which is replaced with a new object every time the JWKS is refreshed.
+func (s *urlSource) KeySet() jwk.Set {
+	// The JWKS was fetched in newURLSource, so this doesn't make network requests
+	set, err := s.cache.Get(context.Background(), s.url)
+	if err != nil {
+		s.log.Errorf("Failed to get JWKS from cache: %v", err)
+		return nil
+	}
+	return set
+}
+
+// Ping implements health.Pinger.
+// It returns an error if the last refresh of the JWKS failed.
+func (s *urlSource) Ping(_ context.Context) error {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	if s.refreshErr != nil {
+		return fmt.Errorf("last refresh of the JWKS failed: %w", s.refreshErr)
+	}
+	return nil
+}
+
+func (s *urlSource) setRefreshErr(err error) {
+	s.lock.Lock()
+	s.refreshErr = err
+	s.lock.Unlock()
+}
@@ -105,6 +105,16 @@ func KeyCanPerformOperation(key jwk.Key, op jwk.KeyOperation) bool {
 	return true
 }
 
+// IsPublicOnlyKey returns true if the key is an asymmetric key that contains only the public part.
+func IsPublicOnlyKey(key jwk.Key) bool {
+	switch key.(type) {
+	case jwk.RSAPublicKey, jwk.ECDSAPublicKey, jwk.OKPPublicKey:
+		return true
+	default:
+		return false
+	}
+}
+
 // KeyCanPerformAlgorithm returns true if the key can be used with a specific algorithm.
 func KeyCanPerformAlgorithm(key jwk.Key, alg string) bool {
 	// "alg" is the supported algorithm
@@ -22,8 +22,13 @@ import (
 	"strings"
 	"time"
 
+	"github.com/cenkalti/backoff/v4"
+	"github.com/hashicorp/golang-lru/v2/expirable"
 	"github.com/lestrrat-go/jwx/v2/jwa"
 	"github.com/lestrrat-go/jwx/v2/jwk"
+	"golang.org/x/sync/singleflight"
+	coreV1 "k8s.io/api/core/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
 	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 
@@ -37,14 +42,26 @@ import (
 const (
 	requestTimeout              = 30 * time.Second
 	metadataKeyDefaultNamespace = "defaultNamespace"
+	// Maximum number of keys stored in the cache.
+	keyCacheMaxSize = 1000
 )
 
+// Returned when a key references a namespace that is not in the list of allowed ones.
+var errNamespaceNotAllowed = errors.New("access denied: the component is not allowed to read secrets from namespace")
+
 type kubeSecretsCrypto struct {
 	contribCrypto.LocalCryptoBaseComponent
 
 	logger     logger.Logger
 	md         secretsMetadata
 	kubeClient kubernetes.Interface
+
+	// Cache of parsed keys, if enabled
+	keyCache *expirable.LRU[string, jwk.Key]
+	// Watcher for secrets, if enabled
+	watcher *secretsWatcher
+	// Coalesces concurrent requests for the same key when the cache is enabled
+	fetchGroup singleflight.Group
 }
 
 // NewKubeSecretsCrypto returns a new Kubernetes secrets crypto provider.
@@ -58,7 +75,7 @@ func NewKubeSecretsCrypto(log logger.Logger) contribCrypto.SubtleCrypto {
 }
 
 // Init the crypto provider.
-func (k *kubeSecretsCrypto) Init(_ context.Context, metadata contribCrypto.Metadata) error {
+func (k *kubeSecretsCrypto) Init(ctx context.Context, metadata contribCrypto.Metadata) error {
 	// Init metadata
 	err := k.md.InitWithMetadata(metadata)
 	if err != nil {
@@ -75,27 +92,112 @@ func (k *kubeSecretsCrypto) Init(_ context.Context, metadata contribCrypto.Metad
 		return fmt.Errorf("failed to init Kubernetes client: %w", err)
 	}
 
+	k.initKeyCache()
+
+	// Start watching secrets if enabled
+	if k.md.WatchSecrets {
+		k.watcher, err = newSecretsWatcher(k.kubeClient, k.md.watchedNamespaces(), k.logger)
+		if err != nil {
+			return fmt.Errorf("failed to init secrets watcher: %w", err)
+		}
+		watchCtx, watchCancel := context.WithTimeout(ctx, requestTimeout)
+		err = k.watcher.Start(watchCtx)
+		watchCancel()
+		if err != nil {
+			k.watcher.Close()
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Close implements the io.Closer interface to close the component.
+func (k *kubeSecretsCrypto) Close() error {
+	if k.watcher != nil {
+		k.watcher.Close()
+	}
 	return nil
 }
 
 // Features returns the features available in this crypto provider.
+// Secrets are read on demand, so this is a static upper bound: operations that require a private or symmetric key fail with ErrKeyPublicOnly if the secret contains a public key only.
 func (k *kubeSecretsCrypto) Features() []contribCrypto.Feature {
-	return []contribCrypto.Feature{} // No Feature supported.
+	return contribCrypto.OperationFeatures(true)
+}
+
+// Creates the cache of keys, if enabled in the metadata.
+func (k *kubeSecretsCrypto) initKeyCache() {
+	if k.md.CacheTTLSeconds <= 0 {
+		k.keyCache = nil
+		return
+	}
+	k.keyCache = expirable.NewLRU[string, jwk.Key](keyCacheMaxSize, nil, time.Duration(k.md.CacheTTLSeconds)*time.Second)
 }
 
 // Retrieves a key (public or private or symmetric) from a Kubernetes secret.
+// If the cache is enabled, keys are retrieved from there when possible.
 func (k *kubeSecretsCrypto) retrieveKeyFromSecret(parentCtx context.Context, key string) (jwk.Key, error) {
 	keyNamespace, keySecret, keyName, err := k.parseKeyString(key)
 	if err != nil {
 		return nil, err
 	}
 
-	// Retrieve the secret
-	ctx, cancel := context.WithTimeout(parentCtx, requestTimeout)
-	res, err := k.kubeClient.CoreV1().
-		Secrets(keyNamespace).
-		Get(ctx, keySecret, metaV1.GetOptions{})
-	cancel()
+	// If we are watching secrets in the namespace, read from the local copy
+	if k.watcher != nil && k.watcher.Watches(keyNamespace) {
+		jwkObj, ok := k.watcher.Get(keyNamespace, keySecret, keyName)
+		if !ok {
+			return nil, contribCrypto.ErrKeyNotFound
+		}
+		return jwkObj, nil
+	}
+
+	if k.keyCache == nil {
+		return k.fetchKey(parentCtx, keyNamespace, keySecret, keyName)
+	}
+
+	cacheKey := keyNamespace + "/" + keySecret + "/" + keyName
+	jwkObj, ok := k.keyCache.Get(cacheKey)
+	if ok {
+		return jwkObj, nil
+	}
+
+	// Concurrent requests for the same key share the same call to the API server
+	// Note that this means that they use the context of the first caller
+	res, err, _ := k.fetchGroup.Do(cacheKey, func() (any, error) {
+		fetched, fErr := k.fetchKey(parentCtx, keyNamespace, keySecret, keyName)
+		if fErr != nil {
+			return nil, fErr
+		}
+		k.keyCache.Add(cacheKey, fetched)
+		return fetched, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.(jwk.Key), nil
+}
+
+// Fetches a key from a Kubernetes secret and parses it.
+func (k *kubeSecretsCrypto) fetchKey(parentCtx context.Context, keyNamespace string, keySecret string, keyName string) (jwk.Key, error) {
+	// Retrieve the secret, retrying in case of transient errors
+	res, err := backoff.RetryNotifyWithData(
+		func() (*coreV1.Secret, error) {
+			ctx, cancel := context.WithTimeout(parentCtx, requestTimeout)
+			defer cancel()
+			secret, rErr := k.kubeClient.CoreV1().
+				Secrets(keyNamespace).
+				Get(ctx, keySecret, metaV1.GetOptions{})
+			if rErr != nil && !isRetryableError(rErr) {
+				return nil, backoff.Permanent(rErr)
+			}
+			return secret, rErr
+		},
+		k.md.backOffConfig.NewBackOffWithContext(parentCtx),
+		func(err error, d time.Duration) {
+			k.logger.Debugf("Failed to retrieve secret '%s/%s', will retry in %v: %v", keyNamespace, keySecret, d, err)
+		},
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -103,8 +205,12 @@ func (k *kubeSecretsCrypto) retrieveKeyFromSecret(parentCtx context.Context, key
 		return nil, contribCrypto.ErrKeyNotFound
 	}
 
-	// Parse the key
-	jwkObj, err := internals.ParseKey(res.Data[keyName], string(res.Type))
+	return parseKey(res.Data[keyName], res.Type)
+}
+
+// Parses a key stored in a secret of the given type.
+func parseKey(data []byte, secretType coreV1.SecretType) (jwk.Key, error) {
+	jwkObj, err := internals.ParseKey(data, string(secretType))
 	if err == nil {
 		switch jwkObj.KeyType() {
 		case jwa.EC, jwa.RSA, jwa.OKP, jwa.OctetSeq:
@@ -120,10 +226,20 @@ func (k *kubeSecretsCrypto) retrieveKeyFromSecret(parentCtx context.Context, key
 	return jwkObj, nil
 }
 
+// isRetryableError returns true if the error returned by the Kubernetes API server is transient.
+func isRetryableError(err error) bool {
+	return apiErrors.IsTooManyRequests(err) ||
+		apiErrors.IsServerTimeout(err) ||
+		apiErrors.IsTimeout(err) ||
+		apiErrors.IsServiceUnavailable(err) ||
+		apiErrors.IsInternalError(err) ||
+		apiErrors.IsUnexpectedServerError(err)
+}
+
 // parseKeyString returns the secret name, key, and optional namespace from the key parameter.
 // If the key parameter doesn't contain a namespace, returns the default one.
 func (k *kubeSecretsCrypto) parseKeyString(param string) (namespace string, secret string, key string, err error) {
-	parts := strings.Split(key, "/")
+	parts := strings.Split(param, "/")
 	switch len(parts) {
 	case 3:
 		namespace = parts[0]
@@ -141,10 +257,14 @@ func (k *kubeSecretsCrypto) parseKeyString(param string) (namespace string, secr
 		err = errors.New("key doesn't have a namespace and the default namespace isn't set")
 	}
 
+	if err == nil && !k.md.isNamespaceAllowed(namespace) {
+		err = fmt.Errorf("%w: '%s'", errNamespaceNotAllowed, namespace)
+	}
+
 	return
 }
 
-func (kubeSecretsCrypto) GetComponentMetadata() (metadataInfo metadata.MetadataMap) {
+func (*kubeSecretsCrypto) GetComponentMetadata() (metadataInfo metadata.MetadataMap) {
 	metadataStruct := secretsMetadata{}
 	metadata.GetMetadataInfoFromStructType(reflect.TypeOf(metadataStruct), &metadataInfo, metadata.CryptoType)
 	return
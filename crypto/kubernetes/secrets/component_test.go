@@ -0,0 +1,390 @@
+/*
+Copyright 2024 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	coreV1 "k8s.io/api/core/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	contribCrypto "github.com/dapr/components-contrib/crypto"
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/kit/logger"
+)
+
+func newTestComponent(t *testing.T, props map[string]string, objects ...runtime.Object) (*kubeSecretsCrypto, *fake.Clientset) {
+	t.Helper()
+
+	k := NewKubeSecretsCrypto(logger.NewLogger("test")).(*kubeSecretsCrypto)
+	err := k.md.InitWithMetadata(contribCrypto.Metadata{Base: metadata.Base{Properties: props}})
+	require.NoError(t, err)
+
+	client := fake.NewSimpleClientset(objects...)
+	k.kubeClient = client
+	k.initKeyCache()
+	return k, client
+}
+
+func TestRetrieveKeyFromSecret(t *testing.T) {
+	secret := &coreV1.Secret{
+		ObjectMeta: metaV1.ObjectMeta{
+			Name:      "mysecret",
+			Namespace: "default",
+		},
+		Data: map[string][]byte{
+			"mykey": []byte(`{"kty":"oct","k":"j4KXb5H0hgjwkXP9qA4OCw"}`),
+		},
+	}
+	secretsResource := schema.GroupResource{Resource: "secrets"}
+
+	t.Run("retries transient errors", func(t *testing.T) {
+		k, client := newTestComponent(t, map[string]string{
+			"defaultNamespace":       "default",
+			"backOffInitialInterval": "1ms",
+		}, secret)
+
+		calls := 0
+		client.PrependReactor("get", "secrets", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			calls++
+			if calls < 3 {
+				return true, nil, apiErrors.NewTooManyRequests("slow down", 0)
+			}
+			return false, nil, nil
+		})
+
+		key, err := k.retrieveKeyFromSecret(context.Background(), "mysecret/mykey")
+		require.NoError(t, err)
+		assert.Equal(t, jwa.OctetSeq, key.KeyType())
+		assert.Equal(t, 3, calls)
+	})
+
+	t.Run("does not retry permanent errors", func(t *testing.T) {
+		k, client := newTestComponent(t, map[string]string{
+			"defaultNamespace":       "default",
+			"backOffInitialInterval": "1ms",
+		}, secret)
+
+		calls := 0
+		client.PrependReactor("get", "secrets", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			calls++
+			return true, nil, apiErrors.NewForbidden(secretsResource, "mysecret", nil)
+		})
+
+		_, err := k.retrieveKeyFromSecret(context.Background(), "mysecret/mykey")
+		require.Error(t, err)
+		assert.True(t, apiErrors.IsForbidden(err))
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("stops after max retries", func(t *testing.T) {
+		k, client := newTestComponent(t, map[string]string{
+			"defaultNamespace":       "default",
+			"backOffInitialInterval": "1ms",
+			"backOffMaxRetries":      "2",
+		}, secret)
+
+		calls := 0
+		client.PrependReactor("get", "secrets", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			calls++
+			return true, nil, apiErrors.NewServiceUnavailable("unavailable")
+		})
+
+		_, err := k.retrieveKeyFromSecret(context.Background(), "mysecret/mykey")
+		require.Error(t, err)
+		assert.True(t, apiErrors.IsServiceUnavailable(err))
+		assert.Equal(t, 3, calls)
+	})
+}
+
+func TestKeyCache(t *testing.T) {
+	secret := &coreV1.Secret{
+		ObjectMeta: metaV1.ObjectMeta{
+			Name:      "mysecret",
+			Namespace: "default",
+		},
+		Data: map[string][]byte{
+			"mykey": []byte(`{"kty":"oct","k":"j4KXb5H0hgjwkXP9qA4OCw"}`),
+		},
+	}
+
+	countGets := func(client *fake.Clientset, delay time.Duration) *atomic.Int32 {
+		calls := &atomic.Int32{}
+		client.PrependReactor("get", "secrets", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			calls.Add(1)
+			time.Sleep(delay)
+			return false, nil, nil
+		})
+		return calls
+	}
+
+	t.Run("cache disabled by default", func(t *testing.T) {
+		k, client := newTestComponent(t, map[string]string{
+			"defaultNamespace": "default",
+		}, secret)
+		calls := countGets(client, 0)
+
+		for i := 0; i < 3; i++ {
+			_, err := k.retrieveKeyFromSecret(context.Background(), "mysecret/mykey")
+			require.NoError(t, err)
+		}
+		assert.Equal(t, int32(3), calls.Load())
+	})
+
+	t.Run("cache reduces calls", func(t *testing.T) {
+		k, client := newTestComponent(t, map[string]string{
+			"defaultNamespace": "default",
+			"cacheTTLSeconds":  "60",
+		}, secret)
+		calls := countGets(client, 0)
+
+		// The key is cached regardless of whether it includes the default namespace
+		for _, key := range []string{"mysecret/mykey", "default/mysecret/mykey", "mysecret/mykey"} {
+			res, err := k.retrieveKeyFromSecret(context.Background(), key)
+			require.NoError(t, err)
+			assert.Equal(t, jwa.OctetSeq, res.KeyType())
+		}
+		assert.Equal(t, int32(1), calls.Load())
+
+		// Errors are not cached
+		for i := 0; i < 2; i++ {
+			_, err := k.retrieveKeyFromSecret(context.Background(), "mysecret/notfound")
+			require.ErrorIs(t, err, contribCrypto.ErrKeyNotFound)
+		}
+		assert.Equal(t, int32(3), calls.Load())
+	})
+
+	t.Run("concurrent lookups are coalesced", func(t *testing.T) {
+		k, client := newTestComponent(t, map[string]string{
+			"defaultNamespace": "default",
+			"cacheTTLSeconds":  "60",
+		}, secret)
+		calls := countGets(client, 200*time.Millisecond)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := k.retrieveKeyFromSecret(context.Background(), "mysecret/mykey")
+				assert.NoError(t, err)
+			}()
+		}
+		wg.Wait()
+		assert.Equal(t, int32(1), calls.Load())
+	})
+}
+
+func TestWatchSecrets(t *testing.T) {
+	newSecret := func(k string) *coreV1.Secret {
+		return &coreV1.Secret{
+			ObjectMeta: metaV1.ObjectMeta{
+				Name:      "mysecret",
+				Namespace: "default",
+			},
+			Data: map[string][]byte{
+				"mykey": []byte(`{"kty":"oct","k":"` + k + `"}`),
+			},
+		}
+	}
+
+	k, client := newTestComponent(t, map[string]string{
+		"defaultNamespace": "default",
+		"watchSecrets":     "true",
+	}, newSecret("j4KXb5H0hgjwkXP9qA4OCw"))
+
+	// Signal when the informer has started watching
+	watchStarted := make(chan struct{})
+	var watchStartedOnce sync.Once
+	client.PrependWatchReactor("secrets", func(action k8stesting.Action) (bool, watch.Interface, error) {
+		watchStartedOnce.Do(func() {
+			close(watchStarted)
+		})
+		return false, nil, nil
+	})
+
+	// Count requests to get individual secrets, which should not happen for the default namespace
+	var gets atomic.Int32
+	client.PrependReactor("get", "secrets", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		gets.Add(1)
+		return false, nil, nil
+	})
+
+	var err error
+	k.watcher, err = newSecretsWatcher(client, k.md.watchedNamespaces(), k.logger)
+	require.NoError(t, err)
+	require.NoError(t, k.watcher.Start(context.Background()))
+	t.Cleanup(func() {
+		k.Close()
+	})
+	select {
+	case <-watchStarted:
+	case <-time.After(5 * time.Second):
+		t.Fatal("informer did not start watching")
+	}
+
+	getKeyBytes := func() []byte {
+		key, err := k.retrieveKeyFromSecret(context.Background(), "mysecret/mykey")
+		require.NoError(t, err)
+		var raw []byte
+		require.NoError(t, key.Raw(&raw))
+		return raw
+	}
+	initial := getKeyBytes()
+
+	// Keys that don't exist are not found
+	_, err = k.retrieveKeyFromSecret(context.Background(), "mysecret/notfound")
+	require.ErrorIs(t, err, contribCrypto.ErrKeyNotFound)
+
+	// Rotate the key
+	_, err = client.CoreV1().Secrets("default").Update(context.Background(), newSecret("AAAAAAAAAAAAAAAAAAAAAA"), metaV1.UpdateOptions{})
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		return !bytes.Equal(initial, getKeyBytes())
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// Delete the secret
+	err = client.CoreV1().Secrets("default").Delete(context.Background(), "mysecret", metaV1.DeleteOptions{})
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		_, err := k.retrieveKeyFromSecret(context.Background(), "mysecret/mykey")
+		return errors.Is(err, contribCrypto.ErrKeyNotFound)
+	}, 5*time.Second, 10*time.Millisecond)
+
+	assert.Equal(t, int32(0), gets.Load())
+}
+
+func TestAllowedNamespaces(t *testing.T) {
+	t.Run("allowed and disallowed namespaces", func(t *testing.T) {
+		k, _ := newTestComponent(t, map[string]string{
+			"defaultNamespace":  "default",
+			"allowedNamespaces": "default, other",
+		})
+
+		ns, _, _, err := k.parseKeyString("mysecret/mykey")
+		require.NoError(t, err)
+		assert.Equal(t, "default", ns)
+
+		ns, _, _, err = k.parseKeyString("other/mysecret/mykey")
+		require.NoError(t, err)
+		assert.Equal(t, "other", ns)
+
+		_, _, _, err = k.parseKeyString("kube-system/mysecret/mykey")
+		require.ErrorIs(t, err, errNamespaceNotAllowed)
+
+		_, err = k.retrieveKeyFromSecret(context.Background(), "kube-system/mysecret/mykey")
+		require.ErrorIs(t, err, errNamespaceNotAllowed)
+	})
+
+	t.Run("all namespaces allowed when unset", func(t *testing.T) {
+		k, _ := newTestComponent(t, map[string]string{
+			"defaultNamespace": "default",
+		})
+
+		_, _, _, err := k.parseKeyString("kube-system/mysecret/mykey")
+		require.NoError(t, err)
+	})
+
+	t.Run("default namespace must be allowed", func(t *testing.T) {
+		var md secretsMetadata
+		err := md.InitWithMetadata(contribCrypto.Metadata{Base: metadata.Base{Properties: map[string]string{
+			"defaultNamespace":  "default",
+			"allowedNamespaces": "other",
+		}}})
+		require.ErrorContains(t, err, "default namespace 'default' is not included")
+	})
+}
+
+func TestWatchedNamespaces(t *testing.T) {
+	initMetadata := func(props map[string]string) (secretsMetadata, error) {
+		var md secretsMetadata
+		err := md.InitWithMetadata(contribCrypto.Metadata{Base: metadata.Base{Properties: props}})
+		return md, err
+	}
+
+	t.Run("default namespace only", func(t *testing.T) {
+		md, err := initMetadata(map[string]string{
+			"defaultNamespace":  "default",
+			"allowedNamespaces": "default,other",
+			"watchSecrets":      "true",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"default"}, md.watchedNamespaces())
+	})
+
+	t.Run("allowed namespaces without default namespace", func(t *testing.T) {
+		md, err := initMetadata(map[string]string{
+			"allowedNamespaces": "ns1, ns2",
+			"watchSecrets":      "true",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"ns1", "ns2"}, md.watchedNamespaces())
+	})
+
+	t.Run("refuses to watch without a namespace scope", func(t *testing.T) {
+		_, err := initMetadata(map[string]string{
+			"watchSecrets": "true",
+		})
+		require.ErrorContains(t, err, "'watchSecrets' requires")
+	})
+
+	t.Run("watcher only lists secrets in the watched namespaces", func(t *testing.T) {
+		client := fake.NewSimpleClientset()
+		var (
+			lock       sync.Mutex
+			namespaces []string
+		)
+		client.PrependReactor("list", "secrets", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			lock.Lock()
+			namespaces = append(namespaces, action.GetNamespace())
+			lock.Unlock()
+			return false, nil, nil
+		})
+
+		w, err := newSecretsWatcher(client, []string{"ns1", "ns2"}, logger.NewLogger("test"))
+		require.NoError(t, err)
+		t.Cleanup(w.Close)
+		require.NoError(t, w.Start(context.Background()))
+
+		lock.Lock()
+		assert.ElementsMatch(t, []string{"ns1", "ns2"}, namespaces)
+		lock.Unlock()
+		assert.True(t, w.Watches("ns1"))
+		assert.True(t, w.Watches("ns2"))
+		assert.False(t, w.Watches("default"))
+		assert.False(t, w.Watches(""))
+	})
+
+	t.Run("watcher requires a namespace", func(t *testing.T) {
+		_, err := newSecretsWatcher(fake.NewSimpleClientset(), nil, logger.NewLogger("test"))
+		require.Error(t, err)
+		_, err = newSecretsWatcher(fake.NewSimpleClientset(), []string{""}, logger.NewLogger("test"))
+		require.Error(t, err)
+	})
+}
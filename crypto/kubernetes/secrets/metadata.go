@@ -14,8 +14,15 @@ limitations under the License.
 package secrets
 
 import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
 	contribCrypto "github.com/dapr/components-contrib/crypto"
 	"github.com/dapr/kit/metadata"
+	"github.com/dapr/kit/retry"
 )
 
 type secretsMetadata struct {
@@ -26,6 +33,27 @@ type secretsMetadata struct {
 	// Path to a kubeconfig file.
 	// If empty, uses the default values.
 	KubeconfigPath string `json:"kubeconfigPath" mapstructure:"kubeconfigPath"`
+
+	// If greater than 0, keys retrieved from secrets are cached in memory for this number of seconds.
+	// This reduces the number of requests to the Kubernetes API server, but changes to secrets may take up to this long to be picked up.
+	// Defaults to 0, which disables the cache.
+	CacheTTLSeconds int `json:"cacheTTLSeconds" mapstructure:"cacheTTLSeconds"`
+
+	// If true, watches secrets in the default namespace (or in the allowed namespaces if defaultNamespace is empty) and keeps a local copy of the keys they contain.
+	// Either defaultNamespace or allowedNamespaces must be set, as secrets are never watched cluster-wide.
+	// Keys are then read from the local copy, which is updated as soon as secrets are changed; keys in other namespaces are still retrieved on demand.
+	// This requires permissions to list and watch secrets.
+	WatchSecrets bool `json:"watchSecrets" mapstructure:"watchSecrets"`
+
+	// If set, the component is only allowed to read secrets from these namespaces, as a comma-separated list.
+	// If defaultNamespace is set, it must be included in this list.
+	// If empty, secrets can be read from any namespace the service account has access to.
+	AllowedNamespaces []string `json:"allowedNamespaces" mapstructure:"allowedNamespaces"`
+
+	// Internal properties
+	// Retry configuration for transient errors returned by the Kubernetes API server.
+	// This is parsed from the properties with the "backOff" prefix, such as "backOffMaxRetries".
+	backOffConfig retry.Config
 }
 
 func (m *secretsMetadata) InitWithMetadata(meta contribCrypto.Metadata) error {
@@ -37,10 +65,56 @@ func (m *secretsMetadata) InitWithMetadata(meta contribCrypto.Metadata) error {
 		return err
 	}
 
+	// Validate the list of allowed namespaces
+	allowed := make([]string, 0, len(m.AllowedNamespaces))
+	for _, ns := range m.AllowedNamespaces {
+		ns = strings.TrimSpace(ns)
+		if ns != "" {
+			allowed = append(allowed, ns)
+		}
+	}
+	m.AllowedNamespaces = allowed
+	if m.DefaultNamespace != "" && !m.isNamespaceAllowed(m.DefaultNamespace) {
+		return fmt.Errorf("default namespace '%s' is not included in 'allowedNamespaces'", m.DefaultNamespace)
+	}
+	if m.WatchSecrets && len(m.watchedNamespaces()) == 0 {
+		return errors.New("'watchSecrets' requires 'defaultNamespace' or 'allowedNamespaces' to be set")
+	}
+
+	// Decode the retry configuration
+	err = retry.DecodeConfigWithPrefix(&m.backOffConfig, meta.Properties, "backOff")
+	if err != nil {
+		return fmt.Errorf("failed to decode backOff configuration: %w", err)
+	}
+
 	return nil
 }
 
 // Reset the object
 func (m *secretsMetadata) reset() {
 	m.DefaultNamespace = ""
+	m.KubeconfigPath = ""
+	m.CacheTTLSeconds = 0
+	m.WatchSecrets = false
+	m.AllowedNamespaces = nil
+
+	// By default, retry transient errors up to 3 times with an exponential backoff
+	m.backOffConfig = retry.DefaultConfig()
+	m.backOffConfig.Policy = retry.PolicyExponential
+	m.backOffConfig.InitialInterval = 200 * time.Millisecond
+	m.backOffConfig.MaxInterval = 2 * time.Second
+	m.backOffConfig.MaxRetries = 3
+}
+
+// Returns the namespaces whose secrets are watched when watchSecrets is enabled.
+func (m *secretsMetadata) watchedNamespaces() []string {
+	if m.DefaultNamespace != "" {
+		return []string{m.DefaultNamespace}
+	}
+	return m.AllowedNamespaces
+}
+
+// Returns true if the component is allowed to read secrets from the namespace.
+func (m *secretsMetadata) isNamespaceAllowed(namespace string) bool {
+	return len(m.AllowedNamespaces) == 0 || slices.Contains(m.AllowedNamespaces, namespace)
 }
@@ -0,0 +1,162 @@
+/*
+Copyright 2024 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/dapr/kit/logger"
+)
+
+// secretsWatcher keeps a local copy of the keys stored in secrets, which is updated by an informer as secrets are changed or deleted.
+type secretsWatcher struct {
+	// Namespaces to watch, each with its own informer
+	namespaces []string
+	logger     logger.Logger
+	factories  []informers.SharedInformerFactory
+	stopCh     chan struct{}
+	stopOnce   sync.Once
+
+	// Parsed keys, grouped by secret: the key of the outer map is "namespace/secretName", and the key of the inner one is the key's name
+	keys map[string]map[string]jwk.Key
+	lock sync.RWMutex
+}
+
+// Secrets are only watched in the namespaces passed here, which must not be empty: the watcher never lists secrets cluster-wide.
+func newSecretsWatcher(client kubernetes.Interface, namespaces []string, logger logger.Logger) (*secretsWatcher, error) {
+	if len(namespaces) == 0 {
+		return nil, errors.New("at least one namespace to watch is required")
+	}
+
+	factories := make([]informers.SharedInformerFactory, len(namespaces))
+	for i, ns := range namespaces {
+		if ns == "" {
+			return nil, errors.New("namespaces to watch must not be empty")
+		}
+		factories[i] = informers.NewSharedInformerFactoryWithOptions(client, 0, informers.WithNamespace(ns))
+	}
+	return &secretsWatcher{
+		namespaces: namespaces,
+		logger:     logger,
+		factories:  factories,
+		stopCh:     make(chan struct{}),
+		keys:       make(map[string]map[string]jwk.Key),
+	}, nil
+}
+
+// Start the informers and wait until the initial list of secrets has been loaded, or ctx is canceled.
+func (w *secretsWatcher) Start(ctx context.Context) error {
+	synced := make([]cache.InformerSynced, len(w.factories))
+	for i, factory := range w.factories {
+		informer := factory.Core().V1().Secrets().Informer()
+		err := w.addEventHandler(informer)
+		if err != nil {
+			return fmt.Errorf("failed to add event handler to the secrets informer for namespace '%s': %w", w.namespaces[i], err)
+		}
+		synced[i] = informer.HasSynced
+		factory.Start(w.stopCh)
+	}
+
+	if !cache.WaitForCacheSync(ctx.Done(), synced...) {
+		return errors.New("failed to sync secrets informer: timed out waiting for the initial list of secrets")
+	}
+
+	return nil
+}
+
+func (w *secretsWatcher) addEventHandler(informer cache.SharedIndexInformer) error {
+	_, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj any) {
+			w.setSecret(obj)
+		},
+		UpdateFunc: func(_, obj any) {
+			w.setSecret(obj)
+		},
+		DeleteFunc: func(obj any) {
+			// If the informer missed the deletion, the object is wrapped in a DeletedFinalStateUnknown
+			tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+			if ok {
+				obj = tombstone.Obj
+			}
+			secret, ok := obj.(*coreV1.Secret)
+			if !ok {
+				return
+			}
+			w.lock.Lock()
+			delete(w.keys, secret.Namespace+"/"+secret.Name)
+			w.lock.Unlock()
+		},
+	})
+	return err
+}
+
+// Watches returns true if secrets in the namespace are being watched.
+func (w *secretsWatcher) Watches(namespace string) bool {
+	return slices.Contains(w.namespaces, namespace)
+}
+
+// Get returns a key from the local copy.
+func (w *secretsWatcher) Get(namespace string, secret string, key string) (jwk.Key, bool) {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+
+	jwkObj, ok := w.keys[namespace+"/"+secret][key]
+	return jwkObj, ok
+}
+
+// Close stops the informer.
+func (w *secretsWatcher) Close() {
+	w.stopOnce.Do(func() {
+		close(w.stopCh)
+		for _, factory := range w.factories {
+			factory.Shutdown()
+		}
+	})
+}
+
+// Parses all keys in a secret that was added or updated, and replaces the keys stored for the secret.
+func (w *secretsWatcher) setSecret(obj any) {
+	secret, ok := obj.(*coreV1.Secret)
+	if !ok {
+		return
+	}
+
+	keys := make(map[string]jwk.Key, len(secret.Data))
+	for name, data := range secret.Data {
+		if len(data) == 0 {
+			continue
+		}
+		jwkObj, err := parseKey(data, secret.Type)
+		if err != nil {
+			// Secrets may contain other values that are not keys, so log errors at debug level only
+			w.logger.Debugf("Ignoring value '%s' in secret '%s/%s': %v", name, secret.Namespace, secret.Name, err)
+			continue
+		}
+		keys[name] = jwkObj
+	}
+
+	w.lock.Lock()
+	w.keys[secret.Namespace+"/"+secret.Name] = keys
+	w.lock.Unlock()
+}
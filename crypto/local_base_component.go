@@ -28,6 +28,9 @@ import (
 // ErrKeyNotFound is returned when the key could not be found.
 var ErrKeyNotFound = errors.New("key not found")
 
+// ErrKeyPublicOnly is returned when an operation requires a private key, but the key contains only the public part.
+var ErrKeyPublicOnly = errors.New("key is public-only")
+
 var (
 	// Token used to populate the list of supported algorithms.
 	supportedAlgsOnce             sync.Once
@@ -92,6 +95,9 @@ func (k LocalCryptoBaseComponent) Decrypt(parentCtx context.Context, ciphertext
 	}
 
 	// Check if the key can perform the operation
+	if IsPublicOnlyKey(key) {
+		return nil, fmt.Errorf("cannot perform the 'decrypt' operation: %w", ErrKeyPublicOnly)
+	}
 	if !KeyCanPerformOperation(key, jwk.KeyOpDecrypt) {
 		return nil, errors.New("key cannot perform the 'decrypt' operation")
 	}
@@ -144,6 +150,9 @@ func (k LocalCryptoBaseComponent) UnwrapKey(parentCtx context.Context, wrappedKe
 	}
 
 	// Check if the key can perform the operation
+	if IsPublicOnlyKey(kek) {
+		return nil, fmt.Errorf("cannot perform the 'unwrapKey' operation: %w", ErrKeyPublicOnly)
+	}
 	if !KeyCanPerformOperation(kek, jwk.KeyOpUnwrapKey) {
 		return nil, errors.New("key cannot perform the 'unwrapKey' operation")
 	}
@@ -174,6 +183,9 @@ func (k LocalCryptoBaseComponent) Sign(parentCtx context.Context, digest []byte,
 	}
 
 	// Check if the key can perform the operation
+	if IsPublicOnlyKey(key) {
+		return nil, fmt.Errorf("cannot perform the 'sign' operation: %w", ErrKeyPublicOnly)
+	}
 	if !KeyCanPerformOperation(key, jwk.KeyOpSign) {
 		return nil, errors.New("key cannot perform the 'sign' operation")
 	}
@@ -60,8 +60,9 @@ func (l *localStorageCrypto) Init(_ context.Context, metadata contribCrypto.Meta
 }
 
 // Features returns the features available in this crypto provider.
+// Keys are read from disk on demand, so this is a static upper bound: operations that require a private or symmetric key fail with ErrKeyPublicOnly if the file contains a public key only.
 func (l *localStorageCrypto) Features() []contribCrypto.Feature {
-	return []contribCrypto.Feature{} // No Feature supported.
+	return contribCrypto.OperationFeatures(true)
 }
 
 // Retrieves a key (public or private or symmetric) from a local file.
@@ -23,6 +23,9 @@ import (
 	kitctx "github.com/dapr/kit/context"
 )
 
+// Default maximum number of keys for which the PubKeyCache keeps statistics.
+const defaultMaxStatsKeys = 1000
+
 // GetKeyFn is the type of the getKeyFn function used by the PubKeyCache.
 type GetKeyFn = func(ctx context.Context, key string) func(resolve func(jwk.Key), reject func(error))
 
@@ -37,6 +40,24 @@ type PubKeyCache struct {
 
 	pubKeys map[string]pubKeyCacheEntry
 	lock    sync.Mutex
+
+	// Statistics are kept for up to maxStatsKeys keys; when the limit is reached, the least recently requested key is evicted
+	// This way, callers requesting many different (including non-existent) key names can't make the map grow indefinitely
+	stats        map[string]*pubKeyCacheStatsEntry
+	statsSeq     uint64
+	maxStatsKeys int
+}
+
+// PubKeyCacheStats contains the number of cache hits and misses for a key.
+type PubKeyCacheStats struct {
+	Hits   uint64
+	Misses uint64
+}
+
+type pubKeyCacheStatsEntry struct {
+	PubKeyCacheStats
+	// Value of statsSeq the last time the key was requested
+	lastUsed uint64
 }
 
 type pubKeyCacheEntry struct {
@@ -47,9 +68,60 @@ type pubKeyCacheEntry struct {
 // NewPubKeyCache returns a new PubKeyCache object
 func NewPubKeyCache(getKeyFn GetKeyFn) *PubKeyCache {
 	return &PubKeyCache{
-		getKeyFn: getKeyFn,
-		pubKeys:  make(map[string]pubKeyCacheEntry),
+		getKeyFn:     getKeyFn,
+		pubKeys:      make(map[string]pubKeyCacheEntry),
+		stats:        make(map[string]*pubKeyCacheStatsEntry),
+		maxStatsKeys: defaultMaxStatsKeys,
+	}
+}
+
+// Stats returns the number of cache hits and misses, for each key that was requested.
+// Statistics are kept for the most recently requested keys only, up to a maximum number of keys.
+func (kc *PubKeyCache) Stats() map[string]PubKeyCacheStats {
+	kc.lock.Lock()
+	defer kc.lock.Unlock()
+
+	res := make(map[string]PubKeyCacheStats, len(kc.stats))
+	for k, v := range kc.stats {
+		res[k] = v.PubKeyCacheStats
+	}
+	return res
+}
+
+// Records a cache hit or miss for the key.
+// Must be invoked while holding a lock.
+func (kc *PubKeyCache) recordStats(key string, hit bool) {
+	kc.statsSeq++
+	st, ok := kc.stats[key]
+	if !ok {
+		if len(kc.stats) >= kc.maxStatsKeys {
+			kc.evictStats()
+		}
+		st = &pubKeyCacheStatsEntry{}
+		kc.stats[key] = st
+	}
+	st.lastUsed = kc.statsSeq
+	if hit {
+		st.Hits++
+	} else {
+		st.Misses++
+	}
+}
+
+// Removes the statistics for the least recently requested key.
+// Must be invoked while holding a lock.
+func (kc *PubKeyCache) evictStats() {
+	var (
+		evict  string
+		oldest uint64
+	)
+	for k, v := range kc.stats {
+		if evict == "" || v.lastUsed < oldest {
+			evict = k
+			oldest = v.lastUsed
+		}
 	}
+	delete(kc.stats, evict)
 }
 
 // GetKey returns a public key from the cache, or uses getKeyFn to request it.
@@ -57,6 +129,7 @@ func (kc *PubKeyCache) GetKey(ctx context.Context, key string) (jwk.Key, error)
 	// Check if the key is in the cache already
 	kc.lock.Lock()
 	p, ok := kc.pubKeys[key]
+	kc.recordStats(key, ok)
 	if ok {
 		// Add the context to the context pool and return the promise (which may
 		// already be resolved).
@@ -19,6 +19,7 @@ import (
 	"crypto/elliptic"
 	"crypto/rand"
 	"errors"
+	"fmt"
 	"sync"
 	"testing"
 	"time"
@@ -363,3 +364,52 @@ func TestPubKeyCacheGetKey(t *testing.T) {
 		close(getKeyReturned)
 	})
 }
+
+func TestPubKeyCacheStats(t *testing.T) {
+	pk, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	testKey, err := jwk.FromRaw(pk.PublicKey)
+	require.NoError(t, err)
+
+	cache := NewPubKeyCache(func(context.Context, string) func(resolve func(jwk.Key), reject func(error)) {
+		return func(resolve func(jwk.Key), reject func(error)) {
+			resolve(testKey)
+		}
+	})
+
+	assert.Empty(t, cache.Stats())
+
+	for i := 0; i < 3; i++ {
+		_, err = cache.GetKey(context.Background(), "key")
+		require.NoError(t, err)
+	}
+	_, err = cache.GetKey(context.Background(), "another-key")
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]PubKeyCacheStats{
+		"key":         {Hits: 2, Misses: 1},
+		"another-key": {Hits: 0, Misses: 1},
+	}, cache.Stats())
+
+	t.Run("evicts the least recently requested key", func(t *testing.T) {
+		cache.maxStatsKeys = 2
+
+		// "key" is now more recent than "another-key"
+		_, err = cache.GetKey(context.Background(), "key")
+		require.NoError(t, err)
+		_, err = cache.GetKey(context.Background(), "third-key")
+		require.NoError(t, err)
+
+		assert.Equal(t, map[string]PubKeyCacheStats{
+			"key":       {Hits: 3, Misses: 1},
+			"third-key": {Hits: 0, Misses: 1},
+		}, cache.Stats())
+
+		// Requesting many keys doesn't grow the map past the limit
+		for i := 0; i < 10; i++ {
+			_, err = cache.GetKey(context.Background(), fmt.Sprintf("key-%d", i))
+			require.NoError(t, err)
+		}
+		assert.Len(t, cache.Stats(), 2)
+	})
+}
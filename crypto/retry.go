@@ -0,0 +1,92 @@
+/*
+Copyright 2024 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crypto
+
+import (
+	"context"
+	"time"
+)
+
+// RetryOptions contains the options for Retry.
+type RetryOptions struct {
+	// Maximum number of attempts, including the first one.
+	// Values lower than 1 are treated as 1, which disables retries.
+	MaxAttempts int
+	// Delay before the first retry. The delay is doubled after each attempt.
+	InitialInterval time.Duration
+	// Maximum delay between attempts.
+	// If the delay requested with RetryAfter is longer than this, the operation is not retried.
+	MaxInterval time.Duration
+	// Returns true if the error is transient and the operation can be retried.
+	// If nil, all errors are retried.
+	IsRetryable func(err error) bool
+	// Returns the delay requested by the server before retrying, for example for throttling, or 0 to use the exponential backoff.
+	// Optional.
+	RetryAfter func(err error) time.Duration
+	// Invoked before waiting to retry the operation.
+	// Optional.
+	OnRetry func(err error, delay time.Duration)
+}
+
+// Retry invokes op, retrying it with an exponential backoff when it returns a transient error.
+// It returns the result of the first successful attempt, or the error of the last one.
+// If the context is canceled while waiting to retry, it returns the context's error.
+// If the context has a deadline that would expire before the next attempt, it returns the last error right away instead of waiting.
+func Retry[T any](ctx context.Context, opts RetryOptions, op func(ctx context.Context) (T, error)) (T, error) {
+	var zero T
+	interval := opts.InitialInterval
+	for attempt := 1; ; attempt++ {
+		res, err := op(ctx)
+		if err == nil {
+			return res, nil
+		}
+
+		if attempt >= opts.MaxAttempts || (opts.IsRetryable != nil && !opts.IsRetryable(err)) || ctx.Err() != nil {
+			return zero, err
+		}
+
+		// Determine how long to wait before retrying
+		delay := interval
+		if opts.RetryAfter != nil {
+			if d := opts.RetryAfter(err); d > 0 {
+				if opts.MaxInterval > 0 && d > opts.MaxInterval {
+					return zero, err
+				}
+				delay = d
+			}
+		}
+		if opts.MaxInterval > 0 && delay > opts.MaxInterval {
+			delay = opts.MaxInterval
+		}
+		interval *= 2
+
+		// Don't wait if the context would expire before the next attempt, so the caller gets the actual error
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return zero, err
+		}
+
+		if opts.OnRetry != nil {
+			opts.OnRetry(err, delay)
+		}
+
+		t := time.NewTimer(delay)
+		select {
+		case <-t.C:
+			// Nop
+		case <-ctx.Done():
+			t.Stop()
+			return zero, ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,160 @@
+/*
+Copyright 2024 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crypto
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetry(t *testing.T) {
+	errTransient := errors.New("transient")
+	errPermanent := errors.New("permanent")
+	opts := RetryOptions{
+		MaxAttempts:     3,
+		InitialInterval: time.Millisecond,
+		MaxInterval:     10 * time.Millisecond,
+		IsRetryable: func(err error) bool {
+			return errors.Is(err, errTransient)
+		},
+	}
+
+	t.Run("succeeds after retry", func(t *testing.T) {
+		attempts := 0
+		var delays []time.Duration
+		opts := opts
+		opts.OnRetry = func(err error, delay time.Duration) {
+			assert.ErrorIs(t, err, errTransient)
+			delays = append(delays, delay)
+		}
+		res, err := Retry(context.Background(), opts, func(ctx context.Context) (string, error) {
+			attempts++
+			if attempts < 3 {
+				return "", errTransient
+			}
+			return "ok", nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "ok", res)
+		assert.Equal(t, 3, attempts)
+		assert.Equal(t, []time.Duration{time.Millisecond, 2 * time.Millisecond}, delays)
+	})
+
+	t.Run("fails after max attempts", func(t *testing.T) {
+		attempts := 0
+		_, err := Retry(context.Background(), opts, func(ctx context.Context) (string, error) {
+			attempts++
+			return "", errTransient
+		})
+		require.ErrorIs(t, err, errTransient)
+		assert.Equal(t, 3, attempts)
+	})
+
+	t.Run("does not retry permanent errors", func(t *testing.T) {
+		attempts := 0
+		_, err := Retry(context.Background(), opts, func(ctx context.Context) (string, error) {
+			attempts++
+			return "", errPermanent
+		})
+		require.ErrorIs(t, err, errPermanent)
+		assert.Equal(t, 1, attempts)
+	})
+
+	t.Run("retries disabled", func(t *testing.T) {
+		attempts := 0
+		opts := opts
+		opts.MaxAttempts = 0
+		_, err := Retry(context.Background(), opts, func(ctx context.Context) (string, error) {
+			attempts++
+			return "", errTransient
+		})
+		require.ErrorIs(t, err, errTransient)
+		assert.Equal(t, 1, attempts)
+	})
+
+	t.Run("uses delay requested with RetryAfter", func(t *testing.T) {
+		var delays []time.Duration
+		opts := opts
+		opts.RetryAfter = func(err error) time.Duration {
+			return 5 * time.Millisecond
+		}
+		opts.OnRetry = func(err error, delay time.Duration) {
+			delays = append(delays, delay)
+		}
+		_, err := Retry(context.Background(), opts, func(ctx context.Context) (string, error) {
+			return "", errTransient
+		})
+		require.ErrorIs(t, err, errTransient)
+		assert.Equal(t, []time.Duration{5 * time.Millisecond, 5 * time.Millisecond}, delays)
+	})
+
+	t.Run("does not retry if RetryAfter is longer than max interval", func(t *testing.T) {
+		attempts := 0
+		opts := opts
+		opts.RetryAfter = func(err error) time.Duration {
+			return time.Minute
+		}
+		_, err := Retry(context.Background(), opts, func(ctx context.Context) (string, error) {
+			attempts++
+			return "", errTransient
+		})
+		require.ErrorIs(t, err, errTransient)
+		assert.Equal(t, 1, attempts)
+	})
+
+	t.Run("returns the last error if the delay is longer than the time left before the deadline", func(t *testing.T) {
+		attempts := 0
+		opts := opts
+		opts.MaxInterval = time.Minute
+		opts.RetryAfter = func(err error) time.Duration {
+			return 45 * time.Second
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		start := time.Now()
+		_, err := Retry(ctx, opts, func(ctx context.Context) (string, error) {
+			attempts++
+			return "", errTransient
+		})
+		require.ErrorIs(t, err, errTransient)
+		assert.Equal(t, 1, attempts)
+		assert.Less(t, time.Since(start), 5*time.Second)
+	})
+
+	t.Run("context cancellation stops waiting", func(t *testing.T) {
+		attempts := 0
+		opts := opts
+		opts.MaxAttempts = 10
+		opts.InitialInterval = time.Minute
+		opts.MaxInterval = time.Minute
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		time.AfterFunc(50*time.Millisecond, cancel)
+		start := time.Now()
+		_, err := Retry(ctx, opts, func(ctx context.Context) (string, error) {
+			attempts++
+			return "", errTransient
+		})
+		require.ErrorIs(t, err, context.Canceled)
+		assert.Equal(t, 1, attempts)
+		assert.Less(t, time.Since(start), 5*time.Second)
+	})
+}
@@ -172,3 +172,23 @@ type SubtleCryptoAlgorithms interface {
 	SupportedEncryptionAlgorithms() []string
 	SupportedSignatureAlgorithms() []string
 }
+
+// SubtleCryptoCacheStats is an optional interface for crypto providers that cache public keys locally, and that can report statistics on cache usage.
+type SubtleCryptoCacheStats interface {
+	// PubKeyCacheStats returns the number of cache hits and misses, for each key that was requested.
+	// Statistics are kept for a bounded number of keys only, evicting the least recently requested ones.
+	PubKeyCacheStats() map[string]PubKeyCacheStats
+}
+
+// SubtleCryptoNonceGenerator is an optional interface for crypto providers that can generate a random nonce when encrypting data with algorithms that require one.
+type SubtleCryptoNonceGenerator interface {
+	// EncryptWithNonce encrypts a small message like Encrypt, but if the algorithm requires a nonce and none is provided, it generates a random one.
+	// It returns the nonce that was used, which is required to decrypt the message.
+	EncryptWithNonce(ctx context.Context, plaintext []byte, algorithm string, keyName string, nonce []byte, associatedData []byte) (ciphertext []byte, tag []byte, usedNonce []byte, err error)
+}
+
+// SubtleCryptoKeyLister is an optional interface for crypto providers that can enumerate the keys stored in the vault.
+type SubtleCryptoKeyLister interface {
+	// ListKeys returns the names of the keys in the vault, without versions.
+	ListKeys(ctx context.Context) ([]string, error)
+}
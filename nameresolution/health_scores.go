@@ -0,0 +1,140 @@
+/*
+Copyright 2024 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nameresolution
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"k8s.io/utils/clock"
+)
+
+const (
+	// Failure scores are halved after this much time without new reports.
+	defaultHealthScoreHalfLife = 30 * time.Second
+	// Scores below this threshold are considered healthy and are removed.
+	healthScoreMinThreshold = 0.01
+)
+
+// HealthScores keeps track of failures reported for addresses, and uses that to pick addresses with a weighted random selection.
+// Each failure increases the score of an address by 1; the score decays over time, and is halved with every success.
+// Addresses are picked with a weight of 1/(1+score), so unhealthy addresses receive less traffic but are not excluded.
+// The zero value is not usable; create an object with NewHealthScores.
+type HealthScores struct {
+	halfLife time.Duration
+	clock    clock.Clock
+	scores   map[string]healthScore
+	lock     sync.Mutex
+
+	// Last time entries whose score decayed below the threshold were removed
+	lastPrune time.Time
+}
+
+type healthScore struct {
+	score   float64
+	updated time.Time
+}
+
+// NewHealthScores returns a new HealthScores object.
+func NewHealthScores() *HealthScores {
+	return &HealthScores{
+		halfLife: defaultHealthScoreHalfLife,
+		clock:    clock.RealClock{},
+		scores:   make(map[string]healthScore),
+	}
+}
+
+// ReportResult records the result of a request sent to an address for an app ID.
+func (h *HealthScores) ReportResult(appID string, address string, success bool) {
+	key := appID + "|" + address
+	now := h.clock.Now()
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	h.pruneIfDue(now)
+
+	score := h.currentScore(h.scores[key], now)
+	if success {
+		score /= 2
+	} else {
+		score++
+	}
+
+	if score < healthScoreMinThreshold {
+		delete(h.scores, key)
+		return
+	}
+	h.scores[key] = healthScore{score: score, updated: now}
+}
+
+// Pick returns an address from the list, using a random selection weighted on the health of each address.
+func (h *HealthScores) Pick(appID string, addresses AddressList) string {
+	if len(addresses) < 2 {
+		return addresses.Pick()
+	}
+
+	now := h.clock.Now()
+	weights := make([]float64, len(addresses))
+	var total float64
+
+	h.lock.Lock()
+	h.pruneIfDue(now)
+	for i, addr := range addresses {
+		weights[i] = 1 / (1 + h.currentScore(h.scores[appID+"|"+addr], now))
+		total += weights[i]
+	}
+	h.lock.Unlock()
+
+	// We use math/rand here as we are just picking a random address, so we don't need a CSPRNG
+	//nolint:gosec
+	n := rand.Float64() * total
+	for i, w := range weights {
+		n -= w
+		if n < 0 {
+			return addresses[i]
+		}
+	}
+	return addresses[len(addresses)-1]
+}
+
+// Removes the entries whose score has decayed below the threshold, so addresses that are not reported anymore don't stay in memory forever.
+// To keep the cost amortized, this scans the map at most once per half-life.
+// Must be invoked while holding the lock.
+func (h *HealthScores) pruneIfDue(now time.Time) {
+	if now.Sub(h.lastPrune) < h.halfLife {
+		return
+	}
+	h.lastPrune = now
+
+	for key, s := range h.scores {
+		if h.currentScore(s, now) < healthScoreMinThreshold {
+			delete(h.scores, key)
+		}
+	}
+}
+
+// Returns the score of an entry, applying the time-based decay.
+func (h *HealthScores) currentScore(s healthScore, now time.Time) float64 {
+	if s.score == 0 {
+		return 0
+	}
+	elapsed := now.Sub(s.updated)
+	if elapsed <= 0 {
+		return s.score
+	}
+	return s.score * math.Pow(0.5, float64(elapsed)/float64(h.halfLife))
+}
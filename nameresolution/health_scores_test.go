@@ -0,0 +1,106 @@
+/*
+Copyright 2024 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nameresolution
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+func TestHealthScores(t *testing.T) {
+	newHealthScores := func() (*HealthScores, *clocktesting.FakeClock) {
+		clock := clocktesting.NewFakeClock(time.Now())
+		h := NewHealthScores()
+		h.clock = clock
+		return h, clock
+	}
+
+	t.Run("failures increase the score", func(t *testing.T) {
+		h, clock := newHealthScores()
+		h.ReportResult("app", "1.1.1.1:1", false)
+		h.ReportResult("app", "1.1.1.1:1", false)
+		assert.InDelta(t, 2.0, h.currentScore(h.scores["app|1.1.1.1:1"], clock.Now()), 0.0001)
+
+		// Other app IDs are not affected
+		assert.Empty(t, h.scores["other|1.1.1.1:1"])
+	})
+
+	t.Run("successes reset the score", func(t *testing.T) {
+		h, clock := newHealthScores()
+		h.ReportResult("app", "1.1.1.1:1", false)
+		h.ReportResult("app", "1.1.1.1:1", true)
+		assert.InDelta(t, 0.5, h.currentScore(h.scores["app|1.1.1.1:1"], clock.Now()), 0.0001)
+
+		for i := 0; i < 10; i++ {
+			h.ReportResult("app", "1.1.1.1:1", true)
+		}
+		assert.NotContains(t, h.scores, "app|1.1.1.1:1")
+	})
+
+	t.Run("score decays over time", func(t *testing.T) {
+		h, clock := newHealthScores()
+		h.ReportResult("app", "1.1.1.1:1", false)
+		clock.Step(defaultHealthScoreHalfLife)
+		assert.InDelta(t, 0.5, h.currentScore(h.scores["app|1.1.1.1:1"], clock.Now()), 0.0001)
+	})
+
+	t.Run("pick prefers healthy addresses", func(t *testing.T) {
+		h, _ := newHealthScores()
+		for i := 0; i < 20; i++ {
+			h.ReportResult("app", "1.1.1.1:1", false)
+		}
+
+		addrs := AddressList{"1.1.1.1:1", "1.1.1.1:2"}
+		counts := map[string]int{}
+		for i := 0; i < 1000; i++ {
+			counts[h.Pick("app", addrs)]++
+		}
+		assert.Greater(t, counts["1.1.1.1:2"], counts["1.1.1.1:1"]*5)
+	})
+
+	t.Run("pick with one or zero addresses", func(t *testing.T) {
+		h, _ := newHealthScores()
+		h.ReportResult("app", "1.1.1.1:1", false)
+		assert.Equal(t, "1.1.1.1:1", h.Pick("app", AddressList{"1.1.1.1:1"}))
+		assert.Equal(t, "", h.Pick("app", AddressList{}))
+	})
+
+	t.Run("decayed entries are pruned", func(t *testing.T) {
+		h, clock := newHealthScores()
+		h.ReportResult("app", "1.1.1.1:1", false)
+		h.ReportResult("app", "1.1.1.1:2", false)
+		h.ReportResult("app", "1.1.1.1:2", false)
+		assert.Len(t, h.scores, 2)
+
+		// After 7 half-lives, a score of 1 is below the threshold, while a score of 2 isn't
+		clock.Step(7 * defaultHealthScoreHalfLife)
+		h.Pick("app", AddressList{"2.2.2.2:1", "2.2.2.2:2"})
+		assert.NotContains(t, h.scores, "app|1.1.1.1:1")
+		assert.Contains(t, h.scores, "app|1.1.1.1:2")
+
+		// Entries are pruned at most once per half-life, so the second entry is kept even if its score is now below the threshold
+		h.ReportResult("other", "3.3.3.3:1", false)
+		clock.Step(defaultHealthScoreHalfLife * 3 / 4)
+		h.Pick("app", AddressList{"2.2.2.2:1", "2.2.2.2:2"})
+		assert.Less(t, h.currentScore(h.scores["app|1.1.1.1:2"], clock.Now()), healthScoreMinThreshold)
+
+		clock.Step(defaultHealthScoreHalfLife / 4)
+		h.ReportResult("app", "2.2.2.2:1", true)
+		assert.NotContains(t, h.scores, "app|1.1.1.1:2")
+		assert.Contains(t, h.scores, "other|3.3.3.3:1")
+	})
+}
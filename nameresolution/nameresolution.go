@@ -32,6 +32,12 @@ type ResolverMulti interface {
 	ResolveIDMulti(ctx context.Context, req ResolveRequest) (AddressList, error)
 }
 
+// ResolverResultReporter is an optional interface for name resolvers that can use feedback on the outcome of requests sent to resolved addresses.
+type ResolverResultReporter interface {
+	// ReportResult reports whether a request sent to an address, resolved for an app ID, succeeded.
+	ReportResult(appID string, address string, success bool)
+}
+
 // ResolveRequest represents service discovery resolver request.
 type ResolveRequest struct {
 	ID        string
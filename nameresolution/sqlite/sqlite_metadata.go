@@ -18,9 +18,11 @@ import (
 	"fmt"
 	"net"
 	"strconv"
+	"strings"
 	"time"
 
 	authSqlite "github.com/dapr/components-contrib/common/authentication/sqlite"
+	sqlnameresolver "github.com/dapr/components-contrib/common/component/sql/nameresolver"
 	"github.com/dapr/components-contrib/nameresolution"
 	"github.com/dapr/kit/metadata"
 )
@@ -29,7 +31,7 @@ const (
 	defaultTableName         = "hosts"
 	defaultMetadataTableName = "metadata"
 	defaultUpdateInterval    = 5 * time.Second
-	defaultCleanupInternal   = time.Hour
+	defaultCleanupInterval   = time.Hour
 
 	// For a nameresolver, we want a fairly low timeout
 	defaultTimeout     = time.Second
@@ -44,6 +46,16 @@ type sqliteMetadata struct {
 	MetadataTableName string        `mapstructure:"metadataTableName"`
 	UpdateInterval    time.Duration `mapstructure:"updateInterval"` // Units smaller than seconds are not accepted
 	CleanupInterval   time.Duration `mapstructure:"cleanupInterval" mapstructurealiases:"cleanupIntervalInSeconds"`
+	// Registrations expire this amount of time after they were last renewed: expired hosts are not resolved, and are deleted by the garbage collector.
+	// Defaults to UpdateInterval; units smaller than seconds are not accepted.
+	HostTTL time.Duration `mapstructure:"hostTTL" mapstructurealiases:"maxAge"`
+	// Strategy for picking an address when there are multiple ones for an app ID: "random" (default) or "roundRobin".
+	ResolutionStrategy string `mapstructure:"resolutionStrategy"`
+	// If true, using an in-memory database is an error rather than a warning, as service invocation across different apps can't work.
+	StrictMode bool `mapstructure:"strictMode"`
+
+	// Internal properties
+	strategy sqlnameresolver.Strategy
 
 	// Instance properties - these are passed by the runtime
 	appID       string
@@ -98,9 +110,41 @@ func (m *sqliteMetadata) InitWithMetadata(meta nameresolution.Metadata) error {
 		return errors.New("update interval must be at least 1s greater than timeout")
 	}
 
+	// HostTTL defaults to UpdateInterval, and cannot be smaller than that or hosts would expire before renewing their registration
+	if m.HostTTL == 0 {
+		m.HostTTL = m.UpdateInterval
+	}
+	if m.HostTTL != m.HostTTL.Truncate(time.Second) {
+		return errors.New("host TTL must not contain fractions of seconds")
+	}
+	if m.HostTTL < m.UpdateInterval {
+		return errors.New("host TTL must be greater than or equal to update interval")
+	}
+
+	// CleanupInterval (if enabled) must be greater than UpdateInterval, or the garbage collector could run more often than hosts renew their registration
+	if m.CleanupInterval > 0 && m.CleanupInterval <= m.UpdateInterval {
+		return fmt.Errorf("cleanup interval (%v) must be greater than update interval (%v), or 0 to disable cleanup", m.CleanupInterval, m.UpdateInterval)
+	}
+
+	m.strategy, err = parseResolutionStrategy(m.ResolutionStrategy)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
+func parseResolutionStrategy(val string) (sqlnameresolver.Strategy, error) {
+	switch strings.ToLower(val) {
+	case "", "random":
+		return sqlnameresolver.StrategyRandom, nil
+	case "roundrobin":
+		return sqlnameresolver.StrategyRoundRobin, nil
+	default:
+		return "", fmt.Errorf("invalid resolution strategy: %s", val)
+	}
+}
+
 func (m sqliteMetadata) GetAddress() string {
 	return net.JoinHostPort(m.hostAddress, strconv.Itoa(m.port))
 }
@@ -116,7 +160,11 @@ func (m *sqliteMetadata) reset() {
 	m.TableName = defaultTableName
 	m.MetadataTableName = defaultMetadataTableName
 	m.UpdateInterval = defaultUpdateInterval
-	m.CleanupInterval = defaultCleanupInternal
+	m.CleanupInterval = defaultCleanupInterval
+	m.HostTTL = 0
+	m.ResolutionStrategy = ""
+	m.StrictMode = false
+	m.strategy = sqlnameresolver.StrategyRandom
 
 	m.appID = ""
 	m.namespace = ""
@@ -61,5 +61,23 @@ func performMigrations(ctx context.Context, db *sql.DB, logger logger.Logger, op
 			}
 			return nil
 		},
+		// Migration 1: add the expires_at column to the hosts table
+		// Hosts running the previous version don't set expires_at, so their rows have the default value of 0; queries treat those as expiring at last_update + TTL
+		// Existing rows are left at 0 too, because their hosts may still be running the previous version and renew their registration by updating last_update only
+		func(ctx context.Context) error {
+			logger.Infof("Adding expires_at column to hosts table '%s'", opts.HostsTableName)
+			_, err := m.GetConn().ExecContext(
+				ctx,
+				fmt.Sprintf(
+					`ALTER TABLE %[1]s ADD COLUMN expires_at INTEGER NOT NULL DEFAULT 0;
+					CREATE INDEX %[1]s_expires_at_idx ON %[1]s (expires_at);`,
+					opts.HostsTableName,
+				),
+			)
+			if err != nil {
+				return fmt.Errorf("failed to add expires_at column to hosts table: %w", err)
+			}
+			return nil
+		},
 	})
 }
@@ -0,0 +1,102 @@
+/*
+Copyright 2024 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sqlite
+
+import (
+	"fmt"
+)
+
+// sqliteQueries implements the sqlnameresolver.Queries interface for SQLite.
+type sqliteQueries struct {
+	metadata *sqliteMetadata
+}
+
+func (q sqliteQueries) RegisterHost() string {
+	// There's a unique index on address
+	// We use REPLACE to take over any previous registration for that address
+	//nolint:gosec
+	return fmt.Sprintf(
+		"REPLACE INTO %s (registration_id, address, app_id, namespace, last_update, expires_at) VALUES (?, ?, ?, ?, unixepoch(CURRENT_TIMESTAMP), unixepoch(CURRENT_TIMESTAMP) + %d)",
+		q.metadata.TableName,
+		int(q.metadata.HostTTL.Seconds()),
+	)
+}
+
+func (q sqliteQueries) RenewRegistration() string {
+	// We use string formatting here for the table name only
+	//nolint:gosec
+	return fmt.Sprintf(
+		"UPDATE %s SET last_update = unixepoch(CURRENT_TIMESTAMP), expires_at = unixepoch(CURRENT_TIMESTAMP) + %d WHERE registration_id = ? AND address = ?",
+		q.metadata.TableName,
+		int(q.metadata.HostTTL.Seconds()),
+	)
+}
+
+func (q sqliteQueries) ResolveID() string {
+	//nolint:gosec
+	return fmt.Sprintf(
+		`SELECT address
+		FROM %s
+		WHERE
+			app_id = ?
+			AND %s > unixepoch(CURRENT_TIMESTAMP)`,
+		q.metadata.TableName,
+		q.expiresAt(),
+	)
+}
+
+func (q sqliteQueries) LookupByAddress() string {
+	//nolint:gosec
+	return fmt.Sprintf(
+		`SELECT DISTINCT app_id
+		FROM %s
+		WHERE
+			address = ?
+			OR address LIKE ? ESCAPE '\'
+		ORDER BY app_id`,
+		q.metadata.TableName,
+	)
+}
+
+func (q sqliteQueries) DeregisterHost() string {
+	return fmt.Sprintf("DELETE FROM %s WHERE registration_id = ? AND address = ?", q.metadata.TableName)
+}
+
+func (q sqliteQueries) UpdateLastCleanup(arg any) (string, any) {
+	return fmt.Sprintf(`INSERT INTO %s (key, value)
+		VALUES ('nr-last-cleanup', CURRENT_TIMESTAMP)
+		ON CONFLICT (key)
+		DO UPDATE SET value = CURRENT_TIMESTAMP
+			WHERE unixepoch(CURRENT_TIMESTAMP) - unixepoch(value)  > ?;`,
+		q.metadata.MetadataTableName,
+	), arg
+}
+
+func (q sqliteQueries) DeleteExpired() string {
+	return fmt.Sprintf(
+		`DELETE FROM %s WHERE %s <= unixepoch(CURRENT_TIMESTAMP)`,
+		q.metadata.TableName,
+		q.expiresAt(),
+	)
+}
+
+// Returns the expression for the expiration time of a row.
+// Hosts running a version of Dapr that predates the expires_at column (while sharing the same database) write rows with expires_at set to 0, and keep updating last_update only.
+// For those rows, the expiration time is computed from last_update, so they are not considered expired.
+func (q sqliteQueries) expiresAt() string {
+	return fmt.Sprintf(
+		"(CASE WHEN expires_at = 0 THEN last_update + %d ELSE expires_at END)",
+		int(q.metadata.HostTTL.Seconds()),
+	)
+}
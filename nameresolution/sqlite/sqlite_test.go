@@ -15,11 +15,14 @@ package sqlite
 
 import (
 	"context"
+	"database/sql"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/require"
 
+	sqlnameresolver "github.com/dapr/components-contrib/common/component/sql/nameresolver"
 	"github.com/dapr/components-contrib/nameresolution"
 	"github.com/dapr/kit/logger"
 )
@@ -61,11 +64,12 @@ func TestSqliteNameResolver(t *testing.T) {
 			{"b0e6cd89", "6.6.6.6:1", "app-6", "", now},
 			{"36e99c68", "7.7.7.7:1", "app-7", "", now},
 			{"f77ed318", "8.8.8.8:1", "app-8", "", now - 100},
+			{"9c2a4e01", "9.9.9.9:1", "app-9", "", now},
+			{"a1d3f5b7", "9.9.9.9:2", "app-10", "", now - 200},
+			{"c4e6a8b0", "9.9.9.9:3", "app-9", "", now},
+			{"5e7f9a1c", "[::1]:1", "app-11", "", now},
 		}
-		for i, r := range rows {
-			_, err := nr.db.Exec("INSERT INTO hosts VALUES (?, ?, ?, ?, ?)", r...)
-			require.NoErrorf(t, err, "Failed to insert row %d", i)
-		}
+		insertTestHosts(t, nr, rows)
 	})
 
 	if t.Failed() {
@@ -112,6 +116,30 @@ func TestSqliteNameResolver(t *testing.T) {
 		}
 	})
 
+	t.Run("Lookup by address", func(t *testing.T) {
+		tt := map[string]struct {
+			address string
+			expect  []string
+		}{
+			"single app ID":              {address: "5.5.5.5:1", expect: []string{"app-5"}},
+			"expired host":               {address: "4.4.4.4:1", expect: []string{"app-4"}},
+			"not found":                  {address: "5.5.5.5:2", expect: []string{}},
+			"host with one app ID":       {address: "1.1.1.1", expect: []string{"app-1"}},
+			"host with multiple app IDs": {address: "9.9.9.9", expect: []string{"app-10", "app-9"}},
+			"host not found":             {address: "10.10.10.10", expect: []string{}},
+			"IPv6 host":                  {address: "::1", expect: []string{"app-11"}},
+			"IPv6 address":               {address: "[::1]:1", expect: []string{"app-11"}},
+			"no partial match":           {address: "9.9.9", expect: []string{}},
+		}
+		for name, tc := range tt {
+			t.Run(name, func(t *testing.T) {
+				res, err := nr.LookupByAddress(tc.address)
+				require.NoError(t, err)
+				require.Equal(t, tc.expect, res)
+			})
+		}
+	})
+
 	// Simulate the ticker
 	t.Run("Renew registration", func(t *testing.T) {
 		t.Run("Succeess", func(t *testing.T) {
@@ -126,7 +154,7 @@ func TestSqliteNameResolver(t *testing.T) {
 			time.Sleep(time.Second)
 
 			// Renew
-			err = nr.doRenewRegistration(context.Background(), addr)
+			err = nr.base.RenewRegistration(context.Background(), addr)
 			require.NoError(t, err)
 
 			// Get updated last_update
@@ -140,9 +168,9 @@ func TestSqliteNameResolver(t *testing.T) {
 
 		t.Run("Lost registration", func(t *testing.T) {
 			// Renew
-			err := nr.doRenewRegistration(context.Background(), "fail")
+			err := nr.base.RenewRegistration(context.Background(), "fail")
 			require.Error(t, err)
-			require.ErrorIs(t, err, errRegistrationLost)
+			require.ErrorIs(t, err, sqlnameresolver.ErrRegistrationLost)
 		})
 	})
 
@@ -151,3 +179,388 @@ func TestSqliteNameResolver(t *testing.T) {
 		require.NoError(t, err)
 	})
 }
+
+func TestSqliteNameResolverStrictMode(t *testing.T) {
+	md := func(connectionString string, strictMode string) nameresolution.Metadata {
+		return nameresolution.Metadata{
+			Instance: nameresolution.Instance{
+				Address:          "127.0.0.1",
+				DaprInternalPort: 1234,
+				AppID:            "myapp",
+			},
+			Configuration: map[string]string{
+				"connectionString": connectionString,
+				"strictMode":       strictMode,
+			},
+		}
+	}
+
+	t.Run("in-memory database is allowed by default", func(t *testing.T) {
+		nr := NewResolver(logger.NewLogger("test")).(*resolver)
+		err := nr.Init(context.Background(), md(":memory:", ""))
+		require.NoError(t, err)
+		require.NoError(t, nr.Close())
+	})
+
+	t.Run("in-memory database is allowed with strict mode off", func(t *testing.T) {
+		nr := NewResolver(logger.NewLogger("test")).(*resolver)
+		err := nr.Init(context.Background(), md(":memory:", "false"))
+		require.NoError(t, err)
+		require.NoError(t, nr.Close())
+	})
+
+	t.Run("in-memory database fails in strict mode", func(t *testing.T) {
+		nr := NewResolver(logger.NewLogger("test")).(*resolver)
+		err := nr.Init(context.Background(), md(":memory:", "true"))
+		require.ErrorContains(t, err, "cannot use an in-memory SQLite database for name resolution in strict mode")
+		require.Nil(t, nr.db)
+		require.NoError(t, nr.Close())
+	})
+
+	t.Run("file database is allowed in strict mode", func(t *testing.T) {
+		nr := NewResolver(logger.NewLogger("test")).(*resolver)
+		err := nr.Init(context.Background(), md(filepath.Join(t.TempDir(), "nr.db"), "true"))
+		require.NoError(t, err)
+		require.NoError(t, nr.Close())
+	})
+}
+
+func TestSqliteNameResolverHostTTL(t *testing.T) {
+	nr := NewResolver(logger.NewLogger("test")).(*resolver)
+	err := nr.Init(context.Background(), nameresolution.Metadata{
+		Instance: nameresolution.Instance{
+			Address:          "127.0.0.1",
+			DaprInternalPort: 1234,
+			AppID:            "myapp",
+		},
+		Configuration: map[string]string{
+			"connectionString": ":memory:",
+			"cleanupInterval":  "0",
+			"updateInterval":   "120s",
+			"hostTTL":          "300s",
+		},
+	})
+	require.NoError(t, err)
+	defer nr.Close()
+
+	now := time.Now().Unix()
+	rows := [][]any{
+		{"2cb5f837", "1.1.1.1:1", "app-1", "", now - 200},            // Older than updateInterval but not hostTTL
+		{"4d1e7b11", "1.1.1.1:2", "app-1", "", now - 400},            // Stale
+		{"f1b24d4b", "2.2.2.2:1", "app-2", "", now - 400},            // Stale
+		{"05add1fa", "1.1.1.1:3", "app-1", "", now - 10, now - 1},    // Updated recently but already expired
+		{"db50a29e", "3.3.3.3:1", "app-3", "", now - 10, now - 1},    // Updated recently but already expired
+		{"eef793d4", "3.3.3.3:2", "app-3", "", now - 200, now + 100}, // Not expired
+		// Rows written by hosts running a previous version, which don't set expires_at
+		{"8c3a61f0", "4.4.4.4:1", "app-4", "", now - 200, int64(0)}, // Not expired
+		{"9a7e02cd", "4.4.4.4:2", "app-4", "", now - 400, int64(0)}, // Stale
+	}
+	insertTestHosts(t, nr, rows)
+
+	t.Run("Expired hosts are not resolved", func(t *testing.T) {
+		// The garbage collector is disabled, so expired rows are still in the table
+		for i := 0; i < 20; i++ {
+			res, err := nr.ResolveID(context.Background(), nameresolution.ResolveRequest{ID: "app-1"})
+			require.NoErrorf(t, err, "Error on iteration %d", i)
+			require.Equal(t, "1.1.1.1:1", res)
+		}
+
+		res, err := nr.ResolveID(context.Background(), nameresolution.ResolveRequest{ID: "app-2"})
+		require.ErrorIs(t, err, ErrNoHost)
+		require.Empty(t, res)
+
+		for i := 0; i < 20; i++ {
+			res, err := nr.ResolveID(context.Background(), nameresolution.ResolveRequest{ID: "app-3"})
+			require.NoErrorf(t, err, "Error on iteration %d", i)
+			require.Equal(t, "3.3.3.3:2", res)
+		}
+
+		for i := 0; i < 20; i++ {
+			res, err := nr.ResolveID(context.Background(), nameresolution.ResolveRequest{ID: "app-4"})
+			require.NoErrorf(t, err, "Error on iteration %d", i)
+			require.Equal(t, "4.4.4.4:1", res)
+		}
+	})
+
+	t.Run("Cleanup deletes expired hosts only", func(t *testing.T) {
+		_, err := nr.db.Exec(sqliteQueries{metadata: &nr.metadata}.DeleteExpired())
+		require.NoError(t, err)
+
+		var addrs []string
+		res, err := nr.db.Query("SELECT address FROM hosts ORDER BY address")
+		require.NoError(t, err)
+		defer res.Close()
+		for res.Next() {
+			var addr string
+			require.NoError(t, res.Scan(&addr))
+			addrs = append(addrs, addr)
+		}
+		require.NoError(t, res.Err())
+		require.Equal(t, []string{"1.1.1.1:1", "127.0.0.1:1234", "3.3.3.3:2", "4.4.4.4:1"}, addrs)
+	})
+}
+
+func TestSqliteNameResolverMigrateExpiresAt(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "nr.db")
+	now := time.Now().Unix()
+
+	// Create the tables as a host running the version before the expires_at column was added, with a registration older than hostTTL
+	db, err := sql.Open("sqlite", dbPath)
+	require.NoError(t, err)
+	_, err = db.Exec(`CREATE TABLE hosts (
+			registration_id TEXT NOT NULL PRIMARY KEY,
+			address TEXT NOT NULL,
+			app_id TEXT NOT NULL,
+			namespace TEXT NOT NULL,
+			last_update INTEGER NOT NULL
+		);
+		CREATE TABLE metadata (
+			key text NOT NULL PRIMARY KEY,
+			value text NOT NULL
+		);
+		INSERT INTO metadata (key, value) VALUES ('nr-migrations', '1');`)
+	require.NoError(t, err)
+	_, err = db.Exec("INSERT INTO hosts (registration_id, address, app_id, namespace, last_update) VALUES ('8c3a61f0', '4.4.4.4:1', 'app-4', '', ?)", now-400)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	// Init performs the migration that adds the expires_at column
+	nr := NewResolver(logger.NewLogger("test")).(*resolver)
+	err = nr.Init(context.Background(), nameresolution.Metadata{
+		Instance: nameresolution.Instance{
+			Address:          "127.0.0.1",
+			DaprInternalPort: 1234,
+			AppID:            "myapp",
+		},
+		Configuration: map[string]string{
+			"connectionString": dbPath,
+			"cleanupInterval":  "0",
+			"updateInterval":   "120s",
+			"hostTTL":          "300s",
+		},
+	})
+	require.NoError(t, err)
+	defer nr.Close()
+
+	// The host is still running the previous version, so it renews its registration by updating last_update only
+	_, err = nr.db.Exec("UPDATE hosts SET last_update = ? WHERE registration_id = '8c3a61f0'", now)
+	require.NoError(t, err)
+
+	res, err := nr.ResolveID(context.Background(), nameresolution.ResolveRequest{ID: "app-4"})
+	require.NoError(t, err)
+	require.Equal(t, "4.4.4.4:1", res)
+
+	// Cleanup does not delete the row
+	_, err = nr.db.Exec(sqliteQueries{metadata: &nr.metadata}.DeleteExpired())
+	require.NoError(t, err)
+	var count int
+	require.NoError(t, nr.db.QueryRow("SELECT COUNT(*) FROM hosts WHERE registration_id = '8c3a61f0'").Scan(&count))
+	require.Equal(t, 1, count)
+}
+
+func TestSqliteNameResolverRoundRobin(t *testing.T) {
+	nr := NewResolver(logger.NewLogger("test")).(*resolver)
+	err := nr.Init(context.Background(), nameresolution.Metadata{
+		Instance: nameresolution.Instance{
+			Address:          "127.0.0.1",
+			DaprInternalPort: 1234,
+			AppID:            "myapp",
+		},
+		Configuration: map[string]string{
+			"connectionString":   ":memory:",
+			"cleanupInterval":    "0",
+			"updateInterval":     "120s",
+			"resolutionStrategy": "roundRobin",
+		},
+	})
+	require.NoError(t, err)
+	defer nr.Close()
+
+	now := time.Now().Unix()
+	rows := [][]any{
+		{"2cb5f837", "1.1.1.1:2", "app-1", "", now},
+		{"4d1e7b11", "1.1.1.1:3", "app-1", "", now},
+		{"05add1fa", "1.1.1.1:1", "app-1", "", now},
+		{"f1b24d4b", "2.2.2.2:1", "app-2", "", now},
+	}
+	insertTestHosts(t, nr, rows)
+
+	resolve := func(appID string) string {
+		res, err := nr.ResolveID(context.Background(), nameresolution.ResolveRequest{ID: appID})
+		require.NoError(t, err)
+		return res
+	}
+
+	// Resolving other app IDs doesn't affect the cycle
+	expect := []string{"1.1.1.1:1", "1.1.1.1:2", "1.1.1.1:3"}
+	for i := 0; i < 9; i++ {
+		require.Equalf(t, expect[i%len(expect)], resolve("app-1"), "Unexpected address on iteration %d", i)
+		require.Equal(t, "2.2.2.2:1", resolve("app-2"))
+	}
+}
+
+func TestSqliteMetadata(t *testing.T) {
+	instance := nameresolution.Instance{
+		Address:          "127.0.0.1",
+		DaprInternalPort: 1234,
+		AppID:            "myapp",
+	}
+
+	t.Run("defaults", func(t *testing.T) {
+		var md sqliteMetadata
+		err := md.InitWithMetadata(nameresolution.Metadata{
+			Instance: instance,
+			Configuration: map[string]string{
+				"connectionString": ":memory:",
+			},
+		})
+		require.NoError(t, err)
+		require.Equal(t, defaultUpdateInterval, md.UpdateInterval)
+		require.Equal(t, defaultCleanupInterval, md.CleanupInterval)
+		require.Equal(t, defaultUpdateInterval, md.HostTTL)
+	})
+
+	t.Run("cleanup interval", func(t *testing.T) {
+		tt := map[string]struct {
+			value     string
+			expectErr bool
+		}{
+			"greater than update interval": {value: "31s"},
+			"disabled":                     {value: "0"},
+			"equal to update interval":     {value: "30s", expectErr: true},
+			"less than update interval":    {value: "10s", expectErr: true},
+		}
+		for name, tc := range tt {
+			t.Run(name, func(t *testing.T) {
+				var md sqliteMetadata
+				err := md.InitWithMetadata(nameresolution.Metadata{
+					Instance: instance,
+					Configuration: map[string]string{
+						"connectionString": ":memory:",
+						"updateInterval":   "30s",
+						"cleanupInterval":  tc.value,
+					},
+				})
+				if tc.expectErr {
+					require.ErrorContains(t, err, "cleanup interval ("+tc.value+") must be greater than update interval (30s)")
+				} else {
+					require.NoError(t, err)
+				}
+			})
+		}
+	})
+
+	t.Run("host TTL defaults to update interval", func(t *testing.T) {
+		var md sqliteMetadata
+		err := md.InitWithMetadata(nameresolution.Metadata{
+			Instance: instance,
+			Configuration: map[string]string{
+				"connectionString": ":memory:",
+				"updateInterval":   "30s",
+			},
+		})
+		require.NoError(t, err)
+		require.Equal(t, 30*time.Second, md.HostTTL)
+	})
+
+	t.Run("host TTL equal to update interval", func(t *testing.T) {
+		var md sqliteMetadata
+		err := md.InitWithMetadata(nameresolution.Metadata{
+			Instance: instance,
+			Configuration: map[string]string{
+				"connectionString": ":memory:",
+				"updateInterval":   "30s",
+				"hostTTL":          "30s",
+			},
+		})
+		require.NoError(t, err)
+		require.Equal(t, 30*time.Second, md.HostTTL)
+	})
+
+	t.Run("maxAge alias", func(t *testing.T) {
+		var md sqliteMetadata
+		err := md.InitWithMetadata(nameresolution.Metadata{
+			Instance: instance,
+			Configuration: map[string]string{
+				"connectionString": ":memory:",
+				"updateInterval":   "30s",
+				"maxAge":           "45s",
+			},
+		})
+		require.NoError(t, err)
+		require.Equal(t, 45*time.Second, md.HostTTL)
+	})
+
+	t.Run("host TTL smaller than update interval", func(t *testing.T) {
+		var md sqliteMetadata
+		err := md.InitWithMetadata(nameresolution.Metadata{
+			Instance: instance,
+			Configuration: map[string]string{
+				"connectionString": ":memory:",
+				"updateInterval":   "30s",
+				"hostTTL":          "29s",
+			},
+		})
+		require.ErrorContains(t, err, "host TTL must be greater than or equal to update interval")
+	})
+
+	t.Run("resolution strategy", func(t *testing.T) {
+		tt := map[string]struct {
+			value     string
+			expect    sqlnameresolver.Strategy
+			expectErr bool
+		}{
+			"default":               {value: "", expect: sqlnameresolver.StrategyRandom},
+			"random":                {value: "random", expect: sqlnameresolver.StrategyRandom},
+			"round robin":           {value: "roundRobin", expect: sqlnameresolver.StrategyRoundRobin},
+			"round robin lowercase": {value: "roundrobin", expect: sqlnameresolver.StrategyRoundRobin},
+			"invalid":               {value: "foo", expectErr: true},
+		}
+		for name, tc := range tt {
+			t.Run(name, func(t *testing.T) {
+				var md sqliteMetadata
+				err := md.InitWithMetadata(nameresolution.Metadata{
+					Instance: instance,
+					Configuration: map[string]string{
+						"connectionString":   ":memory:",
+						"resolutionStrategy": tc.value,
+					},
+				})
+				if tc.expectErr {
+					require.ErrorContains(t, err, "invalid resolution strategy")
+				} else {
+					require.NoError(t, err)
+					require.Equal(t, tc.expect, md.strategy)
+				}
+			})
+		}
+	})
+
+	t.Run("host TTL with fractions of seconds", func(t *testing.T) {
+		var md sqliteMetadata
+		err := md.InitWithMetadata(nameresolution.Metadata{
+			Instance: instance,
+			Configuration: map[string]string{
+				"connectionString": ":memory:",
+				"updateInterval":   "30s",
+				"hostTTL":          "30.5s",
+			},
+		})
+		require.ErrorContains(t, err, "host TTL must not contain fractions of seconds")
+	})
+}
+
+// Inserts rows in the hosts table.
+// Each row contains the registration ID, address, app ID, namespace, last update time, and optionally the expiration time, which defaults to the last update time plus the host TTL.
+func insertTestHosts(t *testing.T, nr *resolver, rows [][]any) {
+	t.Helper()
+
+	ttl := int64(nr.metadata.HostTTL.Seconds())
+	for i, r := range rows {
+		if len(r) == 5 {
+			r = append(r, r[4].(int64)+ttl)
+		}
+		_, err := nr.db.Exec("INSERT INTO hosts (registration_id, address, app_id, namespace, last_update, expires_at) VALUES (?, ?, ?, ?, ?, ?)", r...)
+		require.NoErrorf(t, err, "Failed to insert row %d", i)
+	}
+}
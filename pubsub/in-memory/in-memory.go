@@ -16,6 +16,7 @@ package inmemory
 import (
 	"context"
 	"errors"
+	"fmt"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -32,12 +33,23 @@ type bus struct {
 	closed  atomic.Bool
 	closeCh chan struct{}
 	wg      sync.WaitGroup
+
+	// Active subscriptions, which are added again to the bus when the connection is disrupted
+	// The lock also protects the bus, which is replaced by DisruptConnection
+	subs map[*subscription]struct{}
+	lock sync.RWMutex
+}
+
+type subscription struct {
+	topic   string
+	handler func(data []byte)
 }
 
 func New(logger logger.Logger) pubsub.PubSub {
 	return &bus{
 		log:     logger,
 		closeCh: make(chan struct{}),
+		subs:    make(map[*subscription]struct{}),
 	}
 }
 
@@ -64,7 +76,9 @@ func (a *bus) Publish(_ context.Context, req *pubsub.PublishRequest) error {
 		return errors.New("component is closed")
 	}
 
+	a.lock.RLock()
 	a.bus.Publish(req.Topic, req.Data)
+	a.lock.RUnlock()
 
 	return nil
 }
@@ -77,7 +91,7 @@ func (a *bus) Subscribe(ctx context.Context, req pubsub.SubscribeRequest, handle
 	// For this component we allow built-in retries because it is backed by memory
 	retryHandler := func(data []byte) {
 		for i := 0; i < 10; i++ {
-			handleErr := handler(ctx, &pubsub.NewMessage{Data: data, Topic: req.Topic, Metadata: req.Metadata})
+			handleErr := a.invokeHandler(ctx, handler, &pubsub.NewMessage{Data: data, Topic: req.Topic, Metadata: req.Metadata})
 			if handleErr == nil {
 				break
 			}
@@ -90,7 +104,13 @@ func (a *bus) Subscribe(ctx context.Context, req pubsub.SubscribeRequest, handle
 			}
 		}
 	}
+	sub := &subscription{topic: req.Topic, handler: retryHandler}
+	a.lock.Lock()
 	err := a.bus.SubscribeAsync(req.Topic, retryHandler, true)
+	if err == nil {
+		a.subs[sub] = struct{}{}
+	}
+	a.lock.Unlock()
 	if err != nil {
 		return err
 	}
@@ -103,7 +123,10 @@ func (a *bus) Subscribe(ctx context.Context, req pubsub.SubscribeRequest, handle
 		case <-ctx.Done():
 		case <-a.closeCh:
 		}
+		a.lock.Lock()
+		delete(a.subs, sub)
 		err := a.bus.Unsubscribe(req.Topic, retryHandler)
+		a.lock.Unlock()
 		if err != nil {
 			a.log.Errorf("error while unsubscribing from topic %s: %v", req.Topic, err)
 		}
@@ -112,6 +135,40 @@ func (a *bus) Subscribe(ctx context.Context, req pubsub.SubscribeRequest, handle
 	return nil
 }
 
+// DisruptConnection simulates a loss of the connection by replacing the bus with a new one, and subscribing again to all active topics.
+// Messages that were published but not delivered yet are lost.
+// Implements the pubsub.ConnectionDisrupter interface.
+func (a *bus) DisruptConnection(_ context.Context) error {
+	if a.closed.Load() {
+		return errors.New("component is closed")
+	}
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	newBus := eventbus.New(true)
+	for sub := range a.subs {
+		err := newBus.SubscribeAsync(sub.topic, sub.handler, true)
+		if err != nil {
+			return fmt.Errorf("failed to subscribe again to topic %s: %w", sub.topic, err)
+		}
+	}
+	a.bus = newBus
+
+	return nil
+}
+
+// invokeHandler invokes the handler, returning an error if it panics so the message is retried and the subscription isn't torn down.
+func (a *bus) invokeHandler(ctx context.Context, handler pubsub.Handler, msg *pubsub.NewMessage) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("handler panicked: %v", r)
+		}
+	}()
+
+	return handler(ctx, msg)
+}
+
 // GetComponentMetadata returns the metadata of the component.
 func (a *bus) GetComponentMetadata() (metadataInfo metadata.MetadataMap) {
 	return
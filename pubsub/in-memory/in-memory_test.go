@@ -17,8 +17,10 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/dapr/components-contrib/pubsub"
 	"github.com/dapr/kit/logger"
@@ -102,6 +104,63 @@ func TestRetry(t *testing.T) {
 	assert.Equal(t, 5, i)
 }
 
+func TestRetryAfterPanic(t *testing.T) {
+	bus := New(logger.NewLogger("test"))
+	bus.Init(context.Background(), pubsub.Metadata{})
+
+	ch := make(chan []byte)
+	i := -1
+
+	bus.Subscribe(context.Background(), pubsub.SubscribeRequest{Topic: "demo"}, func(ctx context.Context, msg *pubsub.NewMessage) error {
+		i++
+		if i == 0 {
+			panic("handler panic")
+		}
+
+		return publish(ch, msg)
+	})
+
+	bus.Publish(context.Background(), &pubsub.PublishRequest{Data: []byte("ABCD"), Topic: "demo"})
+	assert.Equal(t, "ABCD", string(<-ch))
+	assert.Equal(t, 1, i)
+
+	// The subscription must still be active
+	bus.Publish(context.Background(), &pubsub.PublishRequest{Data: []byte("EFGH"), Topic: "demo"})
+	assert.Equal(t, "EFGH", string(<-ch))
+}
+
+func TestDisruptConnection(t *testing.T) {
+	ps := New(logger.NewLogger("test"))
+	ps.Init(context.Background(), pubsub.Metadata{})
+
+	ch := make(chan []byte)
+	ps.Subscribe(context.Background(), pubsub.SubscribeRequest{Topic: "demo"}, func(ctx context.Context, msg *pubsub.NewMessage) error {
+		return publish(ch, msg)
+	})
+
+	// Subscriptions whose context is canceled are not restored
+	subscribeCtx, subscribeCancel := context.WithCancel(context.Background())
+	canceledCh := make(chan []byte, 1)
+	ps.Subscribe(subscribeCtx, pubsub.SubscribeRequest{Topic: "demo"}, func(ctx context.Context, msg *pubsub.NewMessage) error {
+		return publish(canceledCh, msg)
+	})
+	subscribeCancel()
+	assert.Eventually(t, func() bool {
+		b := ps.(*bus)
+		b.lock.RLock()
+		defer b.lock.RUnlock()
+		return len(b.subs) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	disrupter, ok := ps.(pubsub.ConnectionDisrupter)
+	require.True(t, ok)
+	require.NoError(t, disrupter.DisruptConnection(context.Background()))
+
+	ps.Publish(context.Background(), &pubsub.PublishRequest{Data: []byte("ABCD"), Topic: "demo"})
+	assert.Equal(t, "ABCD", string(<-ch))
+	assert.Empty(t, canceledCh)
+}
+
 func publish(ch chan []byte, msg *pubsub.NewMessage) error {
 	go func() { ch <- msg.Data }()
 
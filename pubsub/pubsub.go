@@ -49,6 +49,14 @@ type BulkSubscriber interface {
 	BulkSubscribe(ctx context.Context, req SubscribeRequest, bulkHandler BulkHandler) error
 }
 
+// ConnectionDisrupter is an optional interface for message buses that can simulate a transient loss of the connection to the broker.
+// It is used by conformance tests to verify that subscriptions keep receiving messages after the connection is re-established.
+type ConnectionDisrupter interface {
+	// DisruptConnection forcibly closes the connection to the broker, without closing the component or the subscriptions.
+	// The component is expected to re-establish the connection and resume delivering messages.
+	DisruptConnection(ctx context.Context) error
+}
+
 // Handler is the handler used to invoke the app handler.
 type Handler func(ctx context.Context, msg *NewMessage) error
 
@@ -152,6 +152,18 @@ func ConformanceTests(t *testing.T, props map[string]string, component contribCr
 		t.Run("asymmetric signature", algsInList(keys.private, algsSignAsymmetric, sigAlgs))
 	})
 
+	// Components must advertise the operations that can be performed with the keys in the configuration
+	// Operations that require a private or symmetric key are expected only if there's at least one such key (private keys are currently always required)
+	t.Run("features", func(t *testing.T) {
+		fc, ok := component.(interface{ Features() []contribCrypto.Feature })
+		require.True(t, ok, "component does not implement the Features method")
+
+		features := fc.Features()
+		for _, f := range contribCrypto.OperationFeatures(len(keys.private) > 0 || len(keys.symmetric) > 0) {
+			assert.Truef(t, f.IsPresent(features), "feature %s is not advertised by the component", f)
+		}
+	})
+
 	t.Run("GetKey method", func(t *testing.T) {
 		if config.HasOperation(opPublic) {
 			t.Run("Get public keys", func(t *testing.T) {
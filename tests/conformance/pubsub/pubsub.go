@@ -17,12 +17,14 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"maps"
 	"reflect"
 	"slices"
 	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -42,16 +44,39 @@ const (
 	defaultTopicNameBulk          = "testTopicBulk"
 	defaultMultiTopic1Name        = "multiTopic1"
 	defaultMultiTopic2Name        = "multiTopic2"
+	defaultOrderingKeyTopicName   = "orderingKeyTopic"
+	defaultOrderingKeyMetadata    = "partitionKey"
+	defaultPanicTopicName         = "panicTopic"
 	defaultMessageCount           = 10
 	defaultMaxReadDuration        = 60 * time.Second
 	defaultWaitDurationToPublish  = 5 * time.Second
 	defaultCheckInOrderProcessing = true
 	defaultMaxBulkCount           = 5
 	defaultMaxBulkAwaitDurationMs = 500
+	defaultSubscriberFailFirst    = 2
+	defaultSubscriberFailEvery    = 5
+	defaultSubscriberFailTimes    = 1
+	defaultProcessingDelay        = 1 * time.Second
+	defaultOrderingKeyCount       = 10
+	defaultPanicSequence          = 1
+	defaultMaxConcurrency         = 1
+	concurrencyTopicName          = "concurrencyTopic"
+	concurrencyHandlerDuration    = 200 * time.Millisecond
+	reconnectTopicName            = "reconnectTopic"
 	bulkSubStartingKey            = 1000
 	defaultProjectID              = "conformance-test-prj"
 )
 
+// Ordering keys used by the "orderingkey" operation when none are configured.
+var defaultOrderingKeys = []string{"key-a", "key-b", "key-c"}
+
+// Payloads published by the "malformedmessages" operation; "%s" is replaced with the prefix used to recognize them.
+var malformedPayloads = []string{
+	"%s\x00\xff\xfe\xfd",
+	`%s{"truncated":`,
+	"%s" + strings.Repeat("\u00e8", 16),
+}
+
 type TestConfig struct {
 	utils.CommonConfig
 	PubsubName             string            `mapstructure:"pubsubName"`
@@ -67,6 +92,72 @@ type TestConfig struct {
 	WaitDurationToPublish  time.Duration     `mapstructure:"waitDurationToPublish"`
 	CheckInOrderProcessing bool              `mapstructure:"checkInOrderProcessing"`
 	TestProjectID          string            `mapstructure:"testProjectID"`
+	SubscriberErrors       SubscriberErrors  `mapstructure:"subscriberErrors"`
+	ReportLatency          bool              `mapstructure:"reportLatency"`
+	// Time the subscribers wait before returning a simulated error, which allows messages to pile up and be delivered as a batch.
+	// Each simulated error adds this delay to the time needed to read all messages, which must fit within MaxReadDuration.
+	ProcessingDelay time.Duration `mapstructure:"processingDelay"`
+	// If set, asserts that the subscribers always return (ack or nack) within this time.
+	// Must be greater than ProcessingDelay.
+	AckDeadline time.Duration `mapstructure:"ackDeadline"`
+	// Configuration for the "orderingkey" operation.
+	OrderingKey OrderingKeyConfig `mapstructure:"orderingKey"`
+	// Number of messages published in a batch by the "bulkpublish" operation; defaults to MessageCount.
+	BulkMessageCount int `mapstructure:"bulkMessageCount"`
+	// If greater than 0, the "bulkpublish" operation publishes a batch containing an entry of this size in bytes, which must be rejected by the broker, and asserts that only that entry is reported as failed.
+	BulkOversizedEntrySize int `mapstructure:"bulkOversizedEntrySize"`
+	// Name of the topic used by the "handlerpanic" operation.
+	PanicTopicName string `mapstructure:"panicTopicName"`
+	// Sequence number of the message whose first delivery makes the handler panic, in the "handlerpanic" operation.
+	PanicSequence int `mapstructure:"panicSequence"`
+	// Maximum number of concurrent handler invocations allowed in the "maxconcurrency" operation.
+	MaxConcurrency int `mapstructure:"maxConcurrency"`
+	// If set, name of the subscribe metadata property that is set to MaxConcurrency in the "maxconcurrency" operation.
+	// If empty, the limit must be configured with SubscribeMetadata or in the component's metadata.
+	MaxConcurrencyMetadataName string `mapstructure:"maxConcurrencyMetadataName"`
+	// Time the "reconnect" operation waits after disrupting the connection, before publishing more messages.
+	// For components that don't implement pubsub.ConnectionDisrupter, this is the window in which the broker is restarted externally.
+	ReconnectDisruptionWindow time.Duration `mapstructure:"reconnectDisruptionWindow"`
+}
+
+// OrderingKeyConfig configures the "orderingkey" operation, which publishes messages interleaved across multiple ordering keys and asserts that messages with the same key are received in order.
+type OrderingKeyConfig struct {
+	// Name of the topic to use.
+	TopicName string `mapstructure:"topicName"`
+	// Name of the publish metadata property that contains the ordering key, such as "partitionKey".
+	MetadataName string `mapstructure:"metadataName"`
+	// Ordering keys to publish messages with.
+	Keys []string `mapstructure:"keys"`
+	// Number of messages to publish for each key.
+	MessageCount int `mapstructure:"messageCount"`
+}
+
+// SubscriberErrors configures the errors simulated by the subscribers, which cause messages to be redelivered.
+// By default, the first 2 deliveries fail, and after that every 5th delivery fails.
+type SubscriberErrors struct {
+	// Number of deliveries that fail at the beginning of the test.
+	FailFirst int `mapstructure:"failFirst"`
+	// If greater than 0, every N-th delivery fails.
+	FailEvery int `mapstructure:"failEvery"`
+	// Sequence numbers of the messages that fail; each one fails FailTimes times before being processed successfully.
+	FailSequences []int `mapstructure:"failSequences"`
+	// Number of times each message in FailSequences fails.
+	FailTimes int `mapstructure:"failTimes"`
+	// If true, asserts that the number of redeliveries is exactly the number of simulated errors.
+	// Enable this only for brokers that redeliver each failed message once and never redeliver messages that were processed successfully.
+	AssertRedeliveries bool `mapstructure:"assertRedeliveries"`
+}
+
+// Returns true if the delivery should fail.
+// counter is the number of deliveries received so far, errorCount is the number of errors simulated so far, and failures is the number of times the message with the given sequence number has failed.
+func (e SubscriberErrors) shouldFail(counter int, errorCount int, sequence int, failures int) bool {
+	if errorCount < e.FailFirst {
+		return true
+	}
+	if e.FailEvery > 0 && counter%e.FailEvery == 0 {
+		return true
+	}
+	return failures < e.FailTimes && slices.Contains(e.FailSequences, sequence)
 }
 
 func NewTestConfig(componentName string, operations []string, configMap map[string]interface{}) (TestConfig, error) {
@@ -90,9 +181,29 @@ func NewTestConfig(componentName string, operations []string, configMap map[stri
 		CheckInOrderProcessing: defaultCheckInOrderProcessing,
 		TestTopicForBulkSub:    defaultTopicNameBulk,
 		TestProjectID:          defaultProjectID,
+		ProcessingDelay:        defaultProcessingDelay,
+		SubscriberErrors: SubscriberErrors{
+			FailFirst: defaultSubscriberFailFirst,
+			FailEvery: defaultSubscriberFailEvery,
+			FailTimes: defaultSubscriberFailTimes,
+		},
+		OrderingKey: OrderingKeyConfig{
+			TopicName:    defaultOrderingKeyTopicName,
+			MetadataName: defaultOrderingKeyMetadata,
+			MessageCount: defaultOrderingKeyCount,
+		},
+		PanicTopicName: defaultPanicTopicName,
+		PanicSequence:  defaultPanicSequence,
+		MaxConcurrency: defaultMaxConcurrency,
 	}
 
 	err := config.Decode(configMap, &tc)
+	if tc.BulkMessageCount <= 0 {
+		tc.BulkMessageCount = tc.MessageCount
+	}
+	if len(tc.OrderingKey.Keys) == 0 {
+		tc.OrderingKey.Keys = defaultOrderingKeys
+	}
 
 	return tc, err
 }
@@ -132,26 +243,53 @@ func ConformanceTests(t *testing.T, props map[string]string, ps pubsub.PubSub, c
 	processedMessages := make(map[int]struct{}, 20)
 	processedC := make(chan string, config.MessageCount*2)
 	errorCount := 0
+	failedMessages := make(map[int]int, 20)
+	redeliveries := 0
 	dataPrefix := "message-" + runID + "-"
+	malformedPrefix := "malformed-" + runID + "-"
+	malformedC := make(chan struct{}, config.MessageCount*2)
+	awaitingMalformed := 0
 	var outOfOrder bool
 	ctx := context.Background()
 	awaitingMessagesBulk := make(map[string]struct{}, 20)
 	processedMessagesBulk := make(map[int]struct{}, 20)
 	processedCBulk := make(chan string, config.MessageCount*2)
 	errorCountBulk := 0
+	failedMessagesBulk := make(map[int]int, 20)
+	redeliveriesBulk := 0
 	var muBulk sync.Mutex
+	var maxHandlerDuration time.Duration
+	recordHandlerDuration := func(start time.Time) {
+		d := time.Since(start)
+		mu.Lock()
+		maxHandlerDuration = max(maxHandlerDuration, d)
+		mu.Unlock()
+	}
+	var latency *latencyRecorder
+	if config.ReportLatency {
+		latency = newLatencyRecorder()
+	}
 
 	// Subscribe
 	t.Run("subscribe", func(t *testing.T) {
+		if config.AckDeadline > 0 {
+			require.Less(t, config.ProcessingDelay, config.AckDeadline, "processingDelay must be less than ackDeadline")
+		}
+
 		var counter int
 		var lastSequence int
 		err := ps.Subscribe(ctx, pubsub.SubscribeRequest{
 			Topic:    config.TestTopicName,
 			Metadata: config.SubscribeMetadata,
 		}, func(ctx context.Context, msg *pubsub.NewMessage) error {
+			defer recordHandlerDuration(time.Now())
+
 			dataString := string(msg.Data)
 			if !strings.HasPrefix(dataString, dataPrefix) {
 				t.Logf("Ignoring message without expected prefix")
+				if strings.HasPrefix(dataString, malformedPrefix) {
+					malformedC <- struct{}{}
+				}
 
 				return nil
 			}
@@ -164,11 +302,17 @@ func ConformanceTests(t *testing.T, props map[string]string, ps pubsub.PubSub, c
 				return err
 			}
 
+			latency.Received(dataString)
+
 			// Ignore already processed messages
 			// in case we receive a redelivery from the broker
 			// during retries.
 			mu.Lock()
 			_, alreadyProcessed := processedMessages[sequence]
+			failures, failed := failedMessages[sequence]
+			if alreadyProcessed || failed {
+				redeliveries++
+			}
 			mu.Unlock()
 			if alreadyProcessed {
 				t.Logf("Message was already processed: %d", sequence)
@@ -189,13 +333,16 @@ func ConformanceTests(t *testing.T, props map[string]string, ps pubsub.PubSub, c
 				lastSequence = sequence
 			}
 
-			// This behavior is standard to repro a failure of one message in a batch.
-			if errorCount < 2 || counter%5 == 0 {
+			// With the default configuration, this behavior is standard to repro a failure of one message in a batch.
+			if config.SubscriberErrors.shouldFail(counter, errorCount, sequence, failures) {
 				// First message errors just to give time for more messages to pile up.
 				// Second error is to force an error in a batch.
 				errorCount++
+				mu.Lock()
+				failedMessages[sequence]++
+				mu.Unlock()
 				// Sleep to allow messages to pile up and be delivered as a batch.
-				time.Sleep(1 * time.Second)
+				time.Sleep(config.ProcessingDelay)
 				t.Logf("Simulating subscriber error")
 				return errors.New("conf test simulated error")
 			}
@@ -231,6 +378,8 @@ func ConformanceTests(t *testing.T, props map[string]string, ps pubsub.PubSub, c
 					MaxAwaitDurationMs: defaultMaxBulkAwaitDurationMs,
 				},
 			}, func(ctx context.Context, bulkMsg *pubsub.BulkMessage) ([]pubsub.BulkSubscribeResponseEntry, error) {
+				defer recordHandlerDuration(time.Now())
+
 				bulkResponses := make([]pubsub.BulkSubscribeResponseEntry, len(bulkMsg.Entries))
 				hasAnyError := false
 				for i, msg := range bulkMsg.Entries {
@@ -255,6 +404,10 @@ func ConformanceTests(t *testing.T, props map[string]string, ps pubsub.PubSub, c
 					// during retries.
 					muBulk.Lock()
 					_, alreadyProcessed := processedMessagesBulk[sequence]
+					failures, failed := failedMessagesBulk[sequence]
+					if alreadyProcessed || failed {
+						redeliveriesBulk++
+					}
 					muBulk.Unlock()
 					if alreadyProcessed {
 						t.Logf("Message was already processed: %d", sequence)
@@ -276,13 +429,16 @@ func ConformanceTests(t *testing.T, props map[string]string, ps pubsub.PubSub, c
 						lastSequence = sequence
 					}
 
-					// This behavior is standard to repro a failure of one message in a batch.
-					if errorCountBulk < 2 || counter%5 == 0 {
+					// With the default configuration, this behavior is standard to repro a failure of one message in a batch.
+					if config.SubscriberErrors.shouldFail(counter, errorCountBulk, sequence, failures) {
 						// First message errors just to give time for more messages to pile up.
 						// Second error is to force an error in a batch.
 						errorCountBulk++
+						muBulk.Lock()
+						failedMessagesBulk[sequence]++
+						muBulk.Unlock()
 						// Sleep to allow messages to pile up and be delivered as a batch.
-						time.Sleep(1 * time.Second)
+						time.Sleep(config.ProcessingDelay)
 						t.Logf("Simulating subscriber error")
 
 						bulkResponses[i].EntryId = msg.EntryId
@@ -320,6 +476,7 @@ func ConformanceTests(t *testing.T, props map[string]string, ps pubsub.PubSub, c
 
 		for k := 1; k <= config.MessageCount; k++ {
 			data := []byte(fmt.Sprintf("%s%d", dataPrefix, k))
+			latency.Published(string(data))
 			err := ps.Publish(ctx, &pubsub.PublishRequest{
 				Data:       data,
 				PubsubName: config.PubsubName,
@@ -330,6 +487,20 @@ func ConformanceTests(t *testing.T, props map[string]string, ps pubsub.PubSub, c
 				awaitingMessages[string(data)] = struct{}{}
 			}
 			require.NoError(t, err, "expected no error on publishing data %s on topic %s", data, config.TestTopicName)
+
+			// Publish a malformed message after each valid one
+			// The subscriber must ignore them and keep receiving the valid messages
+			if config.HasOperation("malformedmessages") {
+				malformed := []byte(fmt.Sprintf(malformedPayloads[k%len(malformedPayloads)], malformedPrefix))
+				err = ps.Publish(ctx, &pubsub.PublishRequest{
+					Data:       malformed,
+					PubsubName: config.PubsubName,
+					Topic:      config.TestTopicName,
+					Metadata:   config.PublishMetadata,
+				})
+				require.NoError(t, err, "expected no error on publishing malformed data on topic %s", config.TestTopicName)
+				awaitingMalformed++
+			}
 		}
 		if config.HasOperation("bulksubscribe") {
 			_, ok := ps.(pubsub.BulkSubscriber)
@@ -358,7 +529,7 @@ func ConformanceTests(t *testing.T, props map[string]string, ps pubsub.PubSub, c
 		t.Run("bulkPublish", func(t *testing.T) {
 			bP, ok := ps.(pubsub.BulkPublisher)
 			if !ok {
-				t.Fatalf("cannot run bulkPublish conformance, BulkPublisher interface not implemented by the component %s", config.ComponentName)
+				t.Skipf("skipping bulkPublish conformance, BulkPublisher interface not implemented by the component %s", config.ComponentName)
 			}
 			// only run the test if BulkPublish is implemented
 			// Some pubsub, like Kafka need to wait for Subscriber to be up before messages can be consumed.
@@ -368,12 +539,12 @@ func ConformanceTests(t *testing.T, props map[string]string, ps pubsub.PubSub, c
 				PubsubName: config.PubsubName,
 				Topic:      config.TestTopicName,
 				Metadata:   config.PublishMetadata,
-				Entries:    make([]pubsub.BulkMessageEntry, config.MessageCount),
+				Entries:    make([]pubsub.BulkMessageEntry, config.BulkMessageCount),
 			}
 			entryMap := map[string][]byte{}
 			// setting k to one value more than the previously published list of events.
 			// assuming that publish test is run only once and bulkPublish is run right after that
-			for i, k := 0, config.MessageCount+1; i < config.MessageCount; {
+			for i, k := 0, config.MessageCount+1; i < config.BulkMessageCount; {
 				data := []byte(fmt.Sprintf("%s%d", dataPrefix, k))
 				strK := strconv.Itoa(k)
 				req.Entries[i].EntryId = strK
@@ -386,6 +557,10 @@ func ConformanceTests(t *testing.T, props map[string]string, ps pubsub.PubSub, c
 				i++
 			}
 
+			for _, data := range entryMap {
+				latency.Published(string(data))
+			}
+
 			t.Logf("Calling Bulk Publish on component %s", config.ComponentName)
 			// Making use of entryMap defined above here to iterate through entryIds of messages published.
 			res, err := bP.BulkPublish(context.Background(), &req)
@@ -399,10 +574,65 @@ func ConformanceTests(t *testing.T, props map[string]string, ps pubsub.PubSub, c
 					}
 				}
 			}
-			// here only the success case is tested for bulkPublish similar to publish.
-			// For scenarios on partial failures, those will be tested as part of certification tests if possible.
 			require.NoError(t, err, "expected no error on bulk publishing on topic %s", config.TestTopicName)
 		})
+
+		if config.BulkOversizedEntrySize > 0 {
+			t.Run("bulkPublish partial failure", func(t *testing.T) {
+				bP, ok := ps.(pubsub.BulkPublisher)
+				if !ok {
+					t.Skipf("skipping bulkPublish conformance, BulkPublisher interface not implemented by the component %s", config.ComponentName)
+				}
+
+				// Publish a batch of valid entries with an oversized one in the middle
+				// Sequence numbers start after the ones used by the previous batch
+				entries := make([]pubsub.BulkMessageEntry, 0, config.BulkMessageCount+1)
+				entryMap := map[string][]byte{}
+				oversizedID := "oversized"
+				for k := config.MessageCount + config.BulkMessageCount + 1; k <= config.MessageCount+2*config.BulkMessageCount; k++ {
+					if len(entries) == config.BulkMessageCount/2 {
+						entries = append(entries, pubsub.BulkMessageEntry{
+							EntryId:     oversizedID,
+							ContentType: "text/plain",
+							Metadata:    config.PublishMetadata,
+							// Does not have the data prefix, so the subscriber ignores it if it's delivered
+							Event: []byte(strings.Repeat("x", config.BulkOversizedEntrySize)),
+						})
+					}
+
+					data := []byte(fmt.Sprintf("%s%d", dataPrefix, k))
+					strK := strconv.Itoa(k)
+					entries = append(entries, pubsub.BulkMessageEntry{
+						EntryId:     strK,
+						ContentType: "text/plain",
+						Metadata:    config.PublishMetadata,
+						Event:       data,
+					})
+					entryMap[strK] = data
+				}
+
+				for _, data := range entryMap {
+					latency.Published(string(data))
+				}
+
+				res, err := bP.BulkPublish(context.Background(), &pubsub.BulkPublishRequest{
+					PubsubName: config.PubsubName,
+					Topic:      config.TestTopicName,
+					Metadata:   config.PublishMetadata,
+					Entries:    entries,
+				})
+				t.Logf("Bulk publish with an oversized entry returned error: %v", err)
+				failedEntries := convertBulkPublishResponseToStringSlice(res)
+				for k, data := range entryMap {
+					if !slices.Contains(failedEntries, k) {
+						awaitingMessages[string(data)] = struct{}{}
+					}
+				}
+
+				// The component must report the failure for the oversized entry only, rather than failing the whole batch
+				assert.Equal(t, []string{oversizedID}, failedEntries, "expected only the oversized entry to be reported as failed")
+			})
+		}
 	}
 
 	// Verify read
@@ -415,7 +645,10 @@ func ConformanceTests(t *testing.T, props map[string]string, ps pubsub.PubSub, c
 			case processed := <-processedC:
 				t.Logf("deleting %s processed message", processed)
 				delete(awaitingMessages, processed)
-				waiting = len(awaitingMessages) > 0
+				waiting = len(awaitingMessages) > 0 || awaitingMalformed > 0
+			case <-malformedC:
+				awaitingMalformed--
+				waiting = len(awaitingMessages) > 0 || awaitingMalformed > 0
 			case <-timeout:
 				// Break out after the mamimum read duration has elapsed
 				waiting = false
@@ -423,6 +656,25 @@ func ConformanceTests(t *testing.T, props map[string]string, ps pubsub.PubSub, c
 		}
 		assert.False(t, config.CheckInOrderProcessing && outOfOrder, "received messages out of order")
 		assert.Empty(t, awaitingMessages, "expected to read %v messages", config.MessageCount)
+		if config.HasOperation("malformedmessages") {
+			assert.LessOrEqual(t, awaitingMalformed, 0, "expected the subscriber to receive all malformed messages")
+		}
+
+		if config.AckDeadline > 0 {
+			mu.Lock()
+			assert.Less(t, maxHandlerDuration, config.AckDeadline, "expected subscriber to return within the ack deadline")
+			mu.Unlock()
+		}
+
+		if latency != nil {
+			latency.Report(t)
+		}
+
+		if config.SubscriberErrors.AssertRedeliveries {
+			mu.Lock()
+			assert.Equal(t, errorCount, redeliveries, "expected one redelivery for each simulated error")
+			mu.Unlock()
+		}
 	})
 
 	// Verify read on bulk subscription
@@ -447,6 +699,330 @@ func ConformanceTests(t *testing.T, props map[string]string, ps pubsub.PubSub, c
 			}
 			assert.False(t, config.CheckInOrderProcessing && outOfOrder, "received messages out of order")
 			assert.Empty(t, awaitingMessagesBulk, "expected to read %v messages", config.MessageCount)
+
+			if config.SubscriberErrors.AssertRedeliveries {
+				muBulk.Lock()
+				assert.Equal(t, errorCountBulk, redeliveriesBulk, "expected one redelivery for each simulated error")
+				muBulk.Unlock()
+			}
+		})
+	}
+
+	// Per-key ordering
+	if config.HasOperation("orderingkey") {
+		t.Run("ordering key", func(t *testing.T) {
+			orderingKeyPrefix := "ordered-" + runID + "-"
+			subscribeCtx, subscribeCancel := context.WithCancel(context.Background())
+			defer subscribeCancel()
+
+			var orderMu sync.Mutex
+			lastSequences := make(map[string]int, len(config.OrderingKey.Keys))
+			receivedC := make(chan string, len(config.OrderingKey.Keys)*config.OrderingKey.MessageCount)
+			err := ps.Subscribe(subscribeCtx, pubsub.SubscribeRequest{
+				Topic:    config.OrderingKey.TopicName,
+				Metadata: config.SubscribeMetadata,
+			}, func(ctx context.Context, msg *pubsub.NewMessage) error {
+				dataString := string(msg.Data)
+				if !strings.HasPrefix(dataString, orderingKeyPrefix) {
+					t.Logf("Ignoring message without expected prefix")
+					return nil
+				}
+
+				// Payloads are in the format "<prefix><key>-<sequence>"
+				keyAndSequence := dataString[len(orderingKeyPrefix):]
+				idx := strings.LastIndexByte(keyAndSequence, '-')
+				if idx < 0 {
+					assert.Fail(t, "message did not contain a key and sequence number", dataString)
+					return nil
+				}
+				key := keyAndSequence[:idx]
+				sequence, err := strconv.Atoi(keyAndSequence[idx+1:])
+				if err != nil {
+					assert.Fail(t, "message did not contain a sequence number", dataString)
+					return nil
+				}
+
+				orderMu.Lock()
+				defer orderMu.Unlock()
+				last := lastSequences[key]
+				switch {
+				case sequence == last+1:
+					lastSequences[key] = sequence
+					receivedC <- dataString
+				case sequence <= last:
+					// Redeliveries of messages that were already processed are allowed
+					t.Logf("Message was already processed: key=%s sequence=%d", key, sequence)
+				default:
+					assert.Failf(t, "received message out of order", "key=%s expected sequence %d, got %d", key, last+1, sequence)
+				}
+
+				return nil
+			})
+			require.NoError(t, err, "expected no error on subscribe")
+
+			time.Sleep(config.WaitDurationToPublish)
+
+			// Interleave messages across all keys, so they're not in order globally
+			awaiting := make(map[string]struct{}, len(config.OrderingKey.Keys)*config.OrderingKey.MessageCount)
+			for k := 1; k <= config.OrderingKey.MessageCount; k++ {
+				for _, key := range config.OrderingKey.Keys {
+					data := fmt.Sprintf("%s%s-%d", orderingKeyPrefix, key, k)
+					md := maps.Clone(config.PublishMetadata)
+					if md == nil {
+						md = make(map[string]string, 1)
+					}
+					md[config.OrderingKey.MetadataName] = key
+					err = ps.Publish(ctx, &pubsub.PublishRequest{
+						Data:       []byte(data),
+						PubsubName: config.PubsubName,
+						Topic:      config.OrderingKey.TopicName,
+						Metadata:   md,
+					})
+					require.NoError(t, err, "expected no error on publishing data %s on topic %s", data, config.OrderingKey.TopicName)
+					awaiting[data] = struct{}{}
+				}
+			}
+
+			t.Logf("waiting for %v to complete read", config.MaxReadDuration)
+			timeout := time.After(config.MaxReadDuration)
+			for len(awaiting) > 0 {
+				select {
+				case received := <-receivedC:
+					delete(awaiting, received)
+				case <-timeout:
+					assert.Failf(t, "timeout while waiting for messages with ordering keys", "%d messages not received", len(awaiting))
+					return
+				}
+			}
+		})
+	}
+
+	// Redelivery after a handler panic
+	if config.HasOperation("handlerpanic") {
+		t.Run("handler panic", func(t *testing.T) {
+			require.GreaterOrEqual(t, config.PanicSequence, 1, "panicSequence must be between 1 and messageCount")
+			require.LessOrEqual(t, config.PanicSequence, config.MessageCount, "panicSequence must be between 1 and messageCount")
+
+			panicPrefix := "panic-" + runID + "-"
+			panicData := fmt.Sprintf("%s%d", panicPrefix, config.PanicSequence)
+			subscribeCtx, subscribeCancel := context.WithCancel(context.Background())
+			defer subscribeCancel()
+
+			var panicked atomic.Bool
+			receivedC := make(chan string, config.MessageCount*2)
+			err := ps.Subscribe(subscribeCtx, pubsub.SubscribeRequest{
+				Topic:    config.PanicTopicName,
+				Metadata: config.SubscribeMetadata,
+			}, func(ctx context.Context, msg *pubsub.NewMessage) error {
+				dataString := string(msg.Data)
+				if !strings.HasPrefix(dataString, panicPrefix) {
+					t.Logf("Ignoring message without expected prefix")
+					return nil
+				}
+
+				// Panic on the first delivery of the message only
+				if dataString == panicData && panicked.CompareAndSwap(false, true) {
+					t.Logf("Simulating subscriber panic")
+					panic("conf test simulated panic")
+				}
+
+				receivedC <- dataString
+				return nil
+			})
+			require.NoError(t, err, "expected no error on subscribe")
+
+			time.Sleep(config.WaitDurationToPublish)
+
+			awaiting := make(map[string]struct{}, config.MessageCount)
+			for k := 1; k <= config.MessageCount; k++ {
+				data := fmt.Sprintf("%s%d", panicPrefix, k)
+				err = ps.Publish(ctx, &pubsub.PublishRequest{
+					Data:       []byte(data),
+					PubsubName: config.PubsubName,
+					Topic:      config.PanicTopicName,
+					Metadata:   config.PublishMetadata,
+				})
+				require.NoError(t, err, "expected no error on publishing data %s on topic %s", data, config.PanicTopicName)
+				awaiting[data] = struct{}{}
+			}
+
+			t.Logf("waiting for %v to complete read", config.MaxReadDuration)
+			timeout := time.After(config.MaxReadDuration)
+			for len(awaiting) > 0 {
+				select {
+				case received := <-receivedC:
+					delete(awaiting, received)
+				case <-timeout:
+					assert.Failf(t, "timeout while waiting for messages after handler panic", "%d messages not received", len(awaiting))
+					return
+				}
+			}
+			assert.True(t, panicked.Load(), "expected the handler to panic")
+		})
+	}
+
+	// Max concurrency
+	if config.HasOperation("maxconcurrency") {
+		t.Run("max concurrency", func(t *testing.T) {
+			require.Positive(t, config.MaxConcurrency, "maxConcurrency must be greater than 0")
+
+			concurrencyPrefix := "concurrency-" + runID + "-"
+			subscribeCtx, subscribeCancel := context.WithCancel(context.Background())
+			defer subscribeCancel()
+
+			md := maps.Clone(config.SubscribeMetadata)
+			if config.MaxConcurrencyMetadataName != "" {
+				if md == nil {
+					md = make(map[string]string, 1)
+				}
+				md[config.MaxConcurrencyMetadataName] = strconv.Itoa(config.MaxConcurrency)
+			}
+
+			// Publish more messages than the limit, and block each handler so invocations overlap if the component doesn't enforce the limit
+			messageCount := config.MaxConcurrency * 3
+			var inFlight, maxInFlight atomic.Int32
+			receivedC := make(chan string, messageCount*2)
+			err := ps.Subscribe(subscribeCtx, pubsub.SubscribeRequest{
+				Topic:    concurrencyTopicName,
+				Metadata: md,
+			}, func(ctx context.Context, msg *pubsub.NewMessage) error {
+				dataString := string(msg.Data)
+				if !strings.HasPrefix(dataString, concurrencyPrefix) {
+					t.Logf("Ignoring message without expected prefix")
+					return nil
+				}
+
+				n := inFlight.Add(1)
+				defer inFlight.Add(-1)
+				for {
+					cur := maxInFlight.Load()
+					if n <= cur || maxInFlight.CompareAndSwap(cur, n) {
+						break
+					}
+				}
+
+				time.Sleep(concurrencyHandlerDuration)
+				receivedC <- dataString
+				return nil
+			})
+			require.NoError(t, err, "expected no error on subscribe")
+
+			time.Sleep(config.WaitDurationToPublish)
+
+			awaiting := make(map[string]struct{}, messageCount)
+			for k := 1; k <= messageCount; k++ {
+				data := fmt.Sprintf("%s%d", concurrencyPrefix, k)
+				err = ps.Publish(ctx, &pubsub.PublishRequest{
+					Data:       []byte(data),
+					PubsubName: config.PubsubName,
+					Topic:      concurrencyTopicName,
+					Metadata:   config.PublishMetadata,
+				})
+				require.NoError(t, err, "expected no error on publishing data %s on topic %s", data, concurrencyTopicName)
+				awaiting[data] = struct{}{}
+			}
+
+			t.Logf("waiting for %v to complete read", config.MaxReadDuration)
+			timeout := time.After(config.MaxReadDuration)
+		loop:
+			for len(awaiting) > 0 {
+				select {
+				case received := <-receivedC:
+					delete(awaiting, received)
+				case <-timeout:
+					assert.Failf(t, "timeout while waiting for messages", "%d messages not received", len(awaiting))
+					break loop
+				}
+			}
+
+			t.Logf("Maximum number of concurrent handler invocations: %d", maxInFlight.Load())
+			assert.LessOrEqual(t, int(maxInFlight.Load()), config.MaxConcurrency, "expected no more than %d concurrent handler invocations", config.MaxConcurrency)
+		})
+	}
+
+	// Reconnect after a broker restart
+	if config.HasOperation("reconnect") {
+		t.Run("reconnect", func(t *testing.T) {
+			disrupter, ok := ps.(pubsub.ConnectionDisrupter)
+			if !ok && config.ReconnectDisruptionWindow <= 0 {
+				t.Skipf("skipping reconnect conformance, component %s does not implement ConnectionDisrupter and reconnectDisruptionWindow is not set", config.ComponentName)
+			}
+
+			reconnectPrefix := "reconnect-" + runID + "-"
+			subscribeCtx, subscribeCancel := context.WithCancel(context.Background())
+			defer subscribeCancel()
+
+			receivedC := make(chan string, config.MessageCount*4)
+			err := ps.Subscribe(subscribeCtx, pubsub.SubscribeRequest{
+				Topic:    reconnectTopicName,
+				Metadata: config.SubscribeMetadata,
+			}, func(ctx context.Context, msg *pubsub.NewMessage) error {
+				dataString := string(msg.Data)
+				if !strings.HasPrefix(dataString, reconnectPrefix) {
+					t.Logf("Ignoring message without expected prefix")
+					return nil
+				}
+
+				receivedC <- dataString
+				return nil
+			})
+			require.NoError(t, err, "expected no error on subscribe")
+
+			time.Sleep(config.WaitDurationToPublish)
+
+			// Publishes messages with sequence numbers in the range [from, to], retrying while the connection is re-established
+			// Then, waits for all of them to be received
+			publishAndReceive := func(t *testing.T, from, to int) {
+				awaiting := make(map[string]struct{}, to-from+1)
+				deadline := time.Now().Add(config.MaxReadDuration)
+				for k := from; k <= to; k++ {
+					data := fmt.Sprintf("%s%d", reconnectPrefix, k)
+					for {
+						err = ps.Publish(ctx, &pubsub.PublishRequest{
+							Data:       []byte(data),
+							PubsubName: config.PubsubName,
+							Topic:      reconnectTopicName,
+							Metadata:   config.PublishMetadata,
+						})
+						if err == nil || time.Now().After(deadline) {
+							break
+						}
+						t.Logf("Error publishing data %s, will retry: %v", data, err)
+						time.Sleep(time.Second)
+					}
+					require.NoError(t, err, "expected no error on publishing data %s on topic %s", data, reconnectTopicName)
+					awaiting[data] = struct{}{}
+				}
+
+				timeout := time.After(time.Until(deadline))
+				for len(awaiting) > 0 {
+					select {
+					case received := <-receivedC:
+						delete(awaiting, received)
+					case <-timeout:
+						assert.Failf(t, "timeout while waiting for messages", "%d messages not received", len(awaiting))
+						return
+					}
+				}
+			}
+
+			t.Run("before disruption", func(t *testing.T) {
+				publishAndReceive(t, 1, config.MessageCount)
+			})
+
+			if ok {
+				t.Logf("Disrupting the connection to the broker")
+				err = disrupter.DisruptConnection(ctx)
+				require.NoError(t, err, "expected no error disrupting the connection")
+			}
+			if config.ReconnectDisruptionWindow > 0 {
+				t.Logf("Waiting %v for the connection to be disrupted and re-established", config.ReconnectDisruptionWindow)
+				time.Sleep(config.ReconnectDisruptionWindow)
+			}
+
+			t.Run("after disruption", func(t *testing.T) {
+				publishAndReceive(t, config.MessageCount+1, config.MessageCount*2)
+			})
 		})
 	}
 
@@ -628,3 +1204,64 @@ func convertBulkPublishResponseToStringSlice(res pubsub.BulkPublishResponse) []s
 	}
 	return failedEntries
 }
+
+// latencyRecorder records the end-to-end delivery latency of messages, from before they're published until they're first received.
+// Methods are no-ops when invoked on a nil object.
+type latencyRecorder struct {
+	published map[string]time.Time
+	latencies []time.Duration
+	lock      sync.Mutex
+}
+
+func newLatencyRecorder() *latencyRecorder {
+	return &latencyRecorder{
+		published: make(map[string]time.Time),
+	}
+}
+
+// Published records the time a message is published.
+func (r *latencyRecorder) Published(data string) {
+	if r == nil {
+		return
+	}
+
+	r.lock.Lock()
+	r.published[data] = time.Now()
+	r.lock.Unlock()
+}
+
+// Received records the latency of a message the first time it's received.
+func (r *latencyRecorder) Received(data string) {
+	if r == nil {
+		return
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	start, ok := r.published[data]
+	if !ok {
+		return
+	}
+	delete(r.published, data)
+	r.latencies = append(r.latencies, time.Since(start))
+}
+
+// Report logs the min, average, and 95th percentile latency.
+func (r *latencyRecorder) Report(t *testing.T) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if len(r.latencies) == 0 {
+		t.Logf("Delivery latency: no messages received")
+		return
+	}
+
+	sorted := slices.Clone(r.latencies)
+	slices.Sort(sorted)
+	var total time.Duration
+	for _, l := range sorted {
+		total += l
+	}
+	p95 := sorted[(len(sorted)*95+99)/100-1]
+	t.Logf("Delivery latency for %d messages: min=%v avg=%v p95=%v", len(sorted), sorted[0], total/time.Duration(len(sorted)), p95)
+}